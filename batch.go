@@ -0,0 +1,60 @@
+package bktree
+
+import (
+	"slices"
+	"sync"
+)
+
+// FindBatch returns, for each query, all the words in the BK-tree with a
+// distance of n from it, as Find would. Queries with the same normalized form
+// are searched once and share the words found, in results clipped to their
+// length so that appending to one leaves the others untouched. When workers is
+// greater than one, distinct queries are searched concurrently by that many
+// goroutines.
+//
+// The tree must not be modified while FindBatch runs.
+func (t *BKTree) FindBatch(queries [][]byte, n int64, workers int) [][][]byte {
+	r := make([][][]byte, len(queries))
+
+	first := map[string]int{} // Normalized query to index of its first occurrence
+	unique := []int{}         // Indices of first occurrences
+	same := make([]int, len(queries))
+	for i, q := range queries {
+		key := string(t.normalize(q))
+		j, ok := first[key]
+		if !ok {
+			j = i
+			first[key] = i
+			unique = append(unique, i)
+		}
+		same[i] = j
+	}
+
+	if workers <= 1 {
+		for _, i := range unique {
+			r[i] = t.Find(queries[i], n)
+		}
+	} else {
+		next := make(chan int)
+		var wg sync.WaitGroup
+		for w := 0; w < min(workers, len(unique)); w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range next {
+					r[i] = t.Find(queries[i], n)
+				}
+			}()
+		}
+		for _, i := range unique {
+			next <- i
+		}
+		close(next)
+		wg.Wait()
+	}
+
+	for i, j := range same {
+		r[i] = slices.Clip(r[j])
+	}
+	return r
+}
@@ -0,0 +1,67 @@
+package bktree
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFindBatch(t *testing.T) {
+	calls := 0
+	bk := New(func(a, b []byte) int {
+		calls++
+		return levenshteinFromBytes(a, b)
+	})
+	bk.AddAll(wordsOf(dictLg))
+
+	queries := [][]byte{}
+	for i := 0; i < 200; i++ {
+		queries = append(queries, []byte(mess(pick(dictSm), 1)))
+	}
+	queries = append(queries, queries[:50]...)
+
+	calls = 0
+	want := make([][][]byte, len(queries))
+	for i, q := range queries {
+		want[i] = bk.Find(q, 2)
+	}
+	loop := calls
+
+	calls = 0
+	r := bk.FindBatch(queries, 2, 1)
+	if calls >= loop {
+		t.Fatalf("FindBatch made %d metric calls, Find in a loop %d", calls, loop)
+	}
+	checkBatch(t, r, want)
+
+	// The counting metric is not safe for concurrent use.
+	bk.Metric = levenshteinFromBytes
+	checkBatch(t, bk.FindBatch(queries, 2, 8), want)
+	if r := bk.FindBatch(nil, 2, 8); len(r) != 0 {
+		t.Fatalf("FindBatch of no queries returned %d results", len(r))
+	}
+
+	// Results of repeated queries are clipped, so appending to one copies it.
+	r = bk.FindBatch([][]byte{[]byte("book"), []byte("book")}, 2, 1)
+	for i := range r {
+		if len(r[i]) == 0 || cap(r[i]) != len(r[i]) {
+			t.Fatalf("result %d of a repeated query has %d words and capacity %d", i, len(r[i]), cap(r[i]))
+		}
+	}
+}
+
+func checkBatch(t *testing.T, r, want [][][]byte) {
+	t.Helper()
+	if len(r) != len(want) {
+		t.Fatalf("FindBatch returned %d results, want %d", len(r), len(want))
+	}
+	for i := range want {
+		if len(r[i]) != len(want[i]) {
+			t.Fatalf("FindBatch found %d words for query %d, want %d", len(r[i]), i, len(want[i]))
+		}
+		for k := range want[i] {
+			if !bytes.Equal(r[i][k], want[i][k]) {
+				t.Fatalf("FindBatch found %q at %d for query %d, want %q", r[i][k], k, i, want[i][k])
+			}
+		}
+	}
+}
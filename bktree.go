@@ -6,7 +6,16 @@
 package bktree
 
 import (
+	"bytes"
+	"context"
+	"iter"
+	"math"
 	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gogo/protobuf/proto"
 )
@@ -17,8 +26,96 @@ type Metric func(a, b []byte) int
 // BKTree represents a BK-tree with a given metric function.
 type BKTree struct {
 	Metric Metric // Metric function, required
-	root   *Node
-	dirty  bool
+
+	// Normalizer, if set, is applied to words at Add and to queries at Find.
+	// The tree is indexed by the normalized form, while results hold the
+	// bytes originally added.
+	Normalizer Normalizer
+
+	// OnViolation enables a debug mode in which Add spot-checks the metric
+	// axioms along the insertion path and reports every violation found.
+	OnViolation func(v Violation)
+
+	// Observer, if set, is called with the statistics of every search once it
+	// ends. FindBatch may call it from several goroutines at once.
+	Observer func(s QueryStats)
+
+	// ExactIndex, if set, keeps a hash index of the keys from the next Add or
+	// ReadFromFile on, so that Contains and Get take constant time and do not
+	// evaluate the metric. The index holds a map entry, a copy of the key and
+	// a slice for every distinct key, about doubling the memory of a tree of
+	// short words. Without it, Contains and Get follow the path of the key
+	// down the tree, evaluating the metric once per level.
+	ExactIndex bool
+
+	words atomic.Pointer[contents]
+	dirty bool
+
+	mu         sync.Mutex  // Serializes changes to the words with Rebuild
+	rebuilding bool        // Set during an online Rebuild
+	pending    [][2][]byte // Keys and words added during an online Rebuild
+
+	encode     Normalizer  // Turns normalized words into keys, for token and n-gram trees
+	float      FloatMetric // Set for trees created with NewFloat
+	resolution float64
+}
+
+// contents holds the words of a tree. Rebuild replaces it as a whole, so that
+// searches running meanwhile see either the old or the new tree.
+type contents struct {
+	root  *node
+	size  int
+	index map[string][][]byte // Words added under each key, with ExactIndex
+}
+
+// indexed returns the contents of the tree rooted at root, with an index of
+// its keys if withIndex is set.
+func indexed(root *node, withIndex bool) *contents {
+	c := &contents{root: root}
+	if withIndex {
+		c.index = map[string][][]byte{}
+	}
+	if root != nil {
+		root.walk(func(e *node, _ int) bool {
+			if c.index != nil {
+				c.index[string(e.Data)] = append(c.index[string(e.Data)], e.value())
+			}
+			c.size++
+			return true
+		}, 0)
+	}
+	return c
+}
+
+// lookup returns the words stored under key, from the index if there is one
+// and otherwise from the nodes along the path of key, where a metric with
+// d(a, a) == 0 chains copies of a key at distance 0 from one another.
+func (c *contents) lookup(key []byte, m Metric) [][]byte {
+	if c.index != nil {
+		return c.index[string(key)]
+	}
+	var r [][]byte
+	for e := c.root; e != nil; {
+		d := int64(m(e.Data, key))
+		if d == 0 && bytes.Equal(e.Data, key) {
+			r = append(r, e.value())
+		}
+		e = e.child(d)
+	}
+	return r
+}
+
+// insert adds n to the tree and to the index.
+func (c *contents) insert(n *node, m Metric) {
+	if c.root == nil {
+		c.root = n
+	} else {
+		c.root.insert(n, m)
+	}
+	if c.index != nil {
+		c.index[string(n.Data)] = append(c.index[string(n.Data)], n.value())
+	}
+	c.size++
 }
 
 // New returns an initialized BK-tree.
@@ -29,6 +126,18 @@ func New(m Metric) *BKTree {
 	}
 }
 
+// NewFloat returns an initialized BK-tree over a real-valued metric. Entries are
+// placed under integer buckets of width resolution, and searches widen their
+// scan of child buckets so that no entry within the real-valued radius is missed.
+//
+// NewFloat panics if resolution is not positive.
+func NewFloat(m FloatMetric, resolution float64) *BKTree {
+	t := New(Quantize(m, resolution))
+	t.float = m
+	t.resolution = resolution
+	return t
+}
+
 // Reads data from file and deserialize into tree
 func (t *BKTree) ReadFromFile(dbFile string) (err error) {
 	data, err := os.ReadFile(dbFile)
@@ -41,7 +150,7 @@ func (t *BKTree) ReadFromFile(dbFile string) (err error) {
 		return
 	}
 
-	t.root = root
+	t.replace(indexed(fromProto(root), t.ExactIndex))
 
 	return
 }
@@ -49,64 +158,519 @@ func (t *BKTree) ReadFromFile(dbFile string) (err error) {
 // Serializes data and saves into file
 // If tree is empty no operation will be made and 'saved' parameter returns false.
 func (t *BKTree) SaveToFile(filePath string) (saved bool, err error) {
-	saved = false
-	if t.root != nil {
-		var data []byte
-		data, err = proto.Marshal(t.root)
+	root := t.load().root
+	if root == nil {
+		return
+	}
+	data, err := proto.Marshal(root.toProto())
+	if err != nil {
+		return
+	}
+	if err = os.WriteFile(filePath, data, 0644); err != nil {
+		return
+	}
+	t.dirty = false
+	saved = true
+	return
+}
+
+// SaveToFileContext is like SaveToFile, but stops writing when ctx is done.
+// Cancellation is checked before serializing the tree and between chunks of
+// the write; serialization itself runs to completion.
+//
+// Unlike SaveToFile, which overwrites filePath in place, the data is written to
+// a temporary file in the same directory and renamed over filePath once
+// complete, so a cancelled or failed save leaves any previous file in place.
+// This needs write permission on the directory, creates the file with mode
+// 0644 whatever the mode of the previous one, replaces a symbolic link at
+// filePath rather than writing to its target, and detaches filePath from
+// any hard links to the previous file.
+func (t *BKTree) SaveToFileContext(ctx context.Context, filePath string) (saved bool, err error) {
+	root := t.load().root
+	if root == nil {
+		return
+	}
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	data, err := proto.Marshal(root.toProto())
+	if err != nil {
+		return
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(filePath), filepath.Base(filePath)+".*")
+	if err != nil {
+		return
+	}
+	defer func() {
 		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+	for len(data) > 0 {
+		if err = ctx.Err(); err != nil {
 			return
 		}
-		err = os.WriteFile(filePath, data, 0644)
-		if err != nil {
+		n := min(len(data), saveChunkSize)
+		if _, err = f.Write(data[:n]); err != nil {
 			return
 		}
-		t.dirty = false
-		saved = true
+		data = data[n:]
 	}
+	if err = f.Chmod(0644); err != nil {
+		return
+	}
+	if err = f.Close(); err != nil {
+		return
+	}
+	if err = os.Rename(f.Name(), filePath); err != nil {
+		return
+	}
+	t.dirty = false
+	saved = true
 	return
-
 }
 
+// saveChunkSize is the amount of data written between checks for cancellation.
+const saveChunkSize = 1 << 20
+
 // Add inserts a new word to the BK-tree.
 func (t *BKTree) Add(data []byte) {
-	if t.root == nil {
-		t.root = &Node{data, make(map[int64]*Node)}
-	} else {
-		t.root.Add(data, t.Metric)
+	t.add(t.normalize(data), data)
+}
+
+// AddAll inserts every word of data into the BK-tree.
+func (t *BKTree) AddAll(data [][]byte) {
+	t.AddAllContext(context.Background(), data)
+}
+
+// AddAllContext inserts every word of data into the BK-tree, stopping when
+// ctx is done. Words inserted before cancellation are kept in the tree.
+func (t *BKTree) AddAllContext(ctx context.Context, data [][]byte) error {
+	for i, w := range data {
+		if i%contextCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		t.Add(w)
+	}
+	return nil
+}
+
+// add inserts data into the tree under the given key.
+func (t *BKTree) add(key, data []byte) {
+	if t.OnViolation != nil {
+		t.spotCheck(key)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c := t.words.Load()
+	if c == nil {
+		c = indexed(nil, t.ExactIndex)
+		t.words.Store(c)
+	} else if t.ExactIndex && c.index == nil {
+		c = indexed(c.root, true)
+		t.words.Store(c)
+	}
+	c.insert(newNode(key, data), t.Metric)
+	if t.rebuilding {
+		t.pending = append(t.pending, [2][]byte{key, data})
 	}
 	t.dirty = true
 }
 
+// newNode returns a leaf holding data under the given key.
+func newNode(key, data []byte) *node {
+	n := &node{Data: key}
+	if !bytes.Equal(key, data) {
+		n.Value = data
+	}
+	return n
+}
+
+// load returns the current contents of the tree.
+func (t *BKTree) load() *contents {
+	if c := t.words.Load(); c != nil {
+		return c
+	}
+	return indexed(nil, false)
+}
+
+// replace swaps the contents of the tree for c.
+func (t *BKTree) replace(c *contents) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.words.Store(c)
+}
+
+// Len returns the number of words in the BK-tree.
+func (t *BKTree) Len() int {
+	return t.load().size
+}
+
+// Clear removes every word from the BK-tree.
+func (t *BKTree) Clear() {
+	t.replace(nil)
+	t.dirty = true
+}
+
+// Contains reports whether a word with the same normalized form as data has
+// been added. Unlike Find, it compares keys for equality, and with ExactIndex
+// does not evaluate the metric.
+func (t *BKTree) Contains(data []byte) bool {
+	return len(t.load().lookup(t.normalize(data), t.Metric)) > 0
+}
+
+// Get returns the words added with the same normalized form as data, and
+// whether there is any. Without a Normalizer, the number of words returned
+// is how many times data was added.
+func (t *BKTree) Get(data []byte) ([][]byte, bool) {
+	r := t.load().lookup(t.normalize(data), t.Metric)
+	return slices.Clone(r), len(r) > 0
+}
+
+// normalize returns the form under which data is indexed.
+func (t *BKTree) normalize(data []byte) []byte {
+	if t.Normalizer != nil {
+		data = t.Normalizer(data)
+	}
+	if t.encode != nil {
+		data = t.encode(data)
+	}
+	return data
+}
+
+// spotCheck evaluates the metric along the insertion path of data and reports
+// axiom violations to t.OnViolation.
+func (t *BKTree) spotCheck(data []byte) {
+	for _, v := range checkIdentity(data, t.Metric(data, data)) {
+		t.OnViolation(v)
+	}
+	var prev *node
+	var prevDist int
+	for e := t.load().root; e != nil; e = e.child(int64(prevDist)) {
+		d := t.Metric(e.Data, data)
+		for _, v := range checkPair(e.Data, data, d, t.Metric(data, e.Data)) {
+			t.OnViolation(v)
+		}
+		if prev != nil {
+			// e sits at distance prevDist from prev, and prev at prevDist from data
+			if v, ok := checkTriangle(e.Data, prev.Data, data, prevDist, prevDist, d); !ok {
+				t.OnViolation(v)
+			}
+		}
+		prev, prevDist = e, d
+	}
+}
+
+// Match is a word found by a search, along with its distance from the query.
+// For trees created with NewFloat, Distance is the bucket of the real-valued distance.
+type Match struct {
+	Data     []byte
+	Distance int64
+}
+
 // Find returns all the words in the BK-tree with a distance of n from w.
+// For trees created with NewFloat, n counts buckets and the search is exact
+// for the real-valued radius n times the resolution.
 func (t *BKTree) Find(data []byte, n int64) [][]byte {
+	return t.FindRange(data, 0, n)
+}
+
+// FindRange returns all the words in the BK-tree with a distance from data
+// between min and max, inclusive. For trees created with NewFloat, min and
+// max count buckets as in Find.
+func (t *BKTree) FindRange(data []byte, min, max int64) [][]byte {
 	r := [][]byte{}
-	if t.root != nil {
-		r = t.root.Find(data, n, t.Metric, r)
-	}
+	t.run(t.query(data, min, max, func(m Match) bool {
+		r = append(r, m.Data)
+		return true
+	}))
 	return r
 }
 
+// FindFloat returns all the words in a BK-tree created with NewFloat with a
+// real-valued distance of at most r from data. On other trees, it returns the
+// words within the integer distance floor(r).
+func (t *BKTree) FindFloat(data []byte, r float64) [][]byte {
+	res := [][]byte{}
+	if t.float == nil {
+		if !(r >= 0) {
+			return res // Negative or NaN
+		}
+		return t.Find(data, int64(min(math.Floor(r), math.MaxInt32)))
+	}
+	q := t.query(data, 0, 0, func(m Match) bool {
+		res = append(res, m.Data)
+		return true
+	})
+	q.rmax = r
+	t.run(q)
+	return res
+}
+
+// FindContext is like Find, but stops searching when ctx is done, in which
+// case it returns the words found so far along with ctx.Err().
+func (t *BKTree) FindContext(ctx context.Context, data []byte, n int64) ([][]byte, error) {
+	r := [][]byte{}
+	if err := ctx.Err(); err != nil {
+		return r, err
+	}
+	q := t.query(data, 0, n, func(m Match) bool {
+		r = append(r, m.Data)
+		return true
+	})
+	q.ctx = ctx
+	if !t.run(q) {
+		return r, ctx.Err()
+	}
+	return r, nil
+}
+
+// FindOptions bounds the work done by FindWithOptions. Zero fields are not enforced.
+//
+// Each visited node costs one metric evaluation, so MaxMetricCalls and
+// MaxVisited currently bound the same work; either may be set.
+type FindOptions struct {
+	MaxResults     int // Maximum number of words returned
+	MaxMetricCalls int // Maximum number of metric evaluations
+	MaxVisited     int // Maximum number of nodes visited
+
+	Stats *QueryStats // If set, receives the statistics of the search
+}
+
+// FindWithOptions is like Find, but stops searching once one of the limits of
+// opts is reached, in which case truncated is true. Subtrees whose edge
+// distance is closest to the distance of the query are visited first, so
+// truncated results still favour the closest words.
+func (t *BKTree) FindWithOptions(data []byte, n int64, opts FindOptions) (r [][]byte, truncated bool) {
+	r = [][]byte{}
+	q := t.query(data, 0, n, func(m Match) bool {
+		if opts.MaxResults > 0 && len(r) == opts.MaxResults {
+			truncated = true
+			return false
+		}
+		r = append(r, m.Data)
+		return true
+	})
+	q.maxCalls = opts.MaxMetricCalls
+	q.maxVisited = opts.MaxVisited
+	q.stats = opts.Stats
+	t.run(q)
+	return r, truncated || q.truncated
+}
+
+// FindFunc calls fn for every word in the BK-tree with a distance of n from
+// data, as the words are found. The search stops as soon as fn returns false.
+func (t *BKTree) FindFunc(data []byte, n int64, fn func(m Match) bool) {
+	t.run(t.query(data, 0, n, fn))
+}
+
+// Search returns an iterator over the words in the BK-tree with a distance of
+// n from data, and their distances. The tree is traversed lazily, and the
+// traversal stops when the loop over the iterator is broken.
+func (t *BKTree) Search(data []byte, n int64) iter.Seq2[[]byte, int64] {
+	return func(yield func([]byte, int64) bool) {
+		t.run(t.query(data, 0, n, func(m Match) bool {
+			return yield(m.Data, m.Distance)
+		}))
+	}
+}
+
+// All returns an iterator over every word in the BK-tree, in the order of Walk.
+func (t *BKTree) All() iter.Seq[[]byte] {
+	return func(yield func([]byte) bool) {
+		t.Walk(func(data []byte, _ int) bool {
+			return yield(data)
+		})
+	}
+}
+
+// Walk calls fn for every word in the BK-tree along with the depth of its node,
+// the root being at depth 0. Nodes are visited depth-first, children in
+// increasing order of distance from their parent. The walk stops as soon as
+// fn returns false.
+func (t *BKTree) Walk(fn func(data []byte, depth int) bool) {
+	if root := t.load().root; root != nil {
+		root.walk(func(e *node, depth int) bool {
+			return fn(e.value(), depth)
+		}, 0)
+	}
+}
+
+// query holds the parameters and progress of a search.
+type query struct {
+	data     []byte
+	min, max int64
+	metric   Metric
+	fn       func(Match) bool // Called for every match, stops the search by returning false
+	ctx      context.Context  // Optional
+
+	visited, calls, pruned, results int
+	maxVisited, maxCalls            int         // Optional
+	truncated                       bool        // Set when stopped by maxVisited or maxCalls
+	stats                           *QueryStats // Optional
+
+	// Set to search trees created with NewFloat
+	float      FloatMetric
+	resolution float64
+	rmin, rmax float64
+}
+
+// contextCheckInterval is the number of steps between checks for cancellation.
+const contextCheckInterval = 64
+
+// query returns a search of the words with a distance from data between min and max.
+func (t *BKTree) query(data []byte, min, max int64, fn func(Match) bool) *query {
+	q := &query{
+		data:   t.normalize(data),
+		min:    min,
+		max:    max,
+		metric: t.Metric,
+		fn:     fn,
+	}
+	if t.float != nil {
+		q.float = t.float
+		q.resolution = t.resolution
+		q.rmin = float64(min) * t.resolution
+		q.rmax = float64(max) * t.resolution
+	}
+	return q
+}
+
+// run performs q and reports whether the search ran to completion.
+func (t *BKTree) run(q *query) bool {
+	observed := t.Observer != nil || q.stats != nil
+	var start time.Time
+	if observed {
+		start = time.Now()
+	}
+
+	done := true
+	if root := t.load().root; root != nil && q.float != nil {
+		done = root.searchFloat(q)
+	} else if root != nil {
+		done = root.search(q)
+	}
+
+	if observed {
+		s := QueryStats{
+			Visited:     q.visited,
+			MetricCalls: q.calls,
+			Pruned:      q.pruned,
+			Results:     q.results,
+			Elapsed:     time.Since(start),
+		}
+		if q.stats != nil {
+			*q.stats = s
+		}
+		if t.Observer != nil {
+			t.Observer(s)
+		}
+	}
+	return done
+}
+
+// match passes a word found by the search to q.fn.
+func (q *query) match(m Match) bool {
+	q.results++
+	return q.fn(m)
+}
+
+// next is called before measuring each node and reports whether the search may go on.
+func (q *query) next() bool {
+	if q.maxVisited > 0 && q.visited >= q.maxVisited || q.maxCalls > 0 && q.calls >= q.maxCalls {
+		q.truncated = true
+		return false
+	}
+	q.visited++
+	q.calls++
+	if q.ctx != nil && q.visited%contextCheckInterval == 0 && q.ctx.Err() != nil {
+		return false
+	}
+	return true
+}
+
 func (e *Node) Add(data []byte, m Metric) {
-	d := int64(m(e.Data, data))
-	if c, ok := e.Children[d]; !ok {
-		e.Children[d] = &Node{data, make(map[int64]*Node)}
+	e.insert(&Node{Data: data, Children: make(map[int64]*Node)}, m)
+}
+
+func (e *Node) insert(n *Node, m Metric) {
+	d := int64(m(e.Data, n.Data))
+	if c, ok := e.Children[d]; !ok || c == nil {
+		if e.Children == nil {
+			e.Children = make(map[int64]*Node) // Leaves read from a file have no map
+		}
+		e.Children[d] = n
 	} else {
-		c.Add(data, m)
+		c.insert(n, m)
 	}
 }
 
+// value returns the bytes originally added for e.
+func (e *Node) value() []byte {
+	if e.Value != nil {
+		return e.Value
+	}
+	return e.Data
+}
+
 func (e *Node) Find(data []byte, n int64, m Metric, r [][]byte) [][]byte {
 	l := int64(m(e.Data, data))
 	if l <= n {
-		r = append(r, e.Data)
+		r = append(r, e.value())
 	}
-	for i := l - n; i <= l+n; i++ {
-		if i < 0 {
-			continue // Skip negative distances
-		}
-		if c, ok := e.Children[i]; ok {
-			r = c.Find(data, n, m, r)
+	for k := int64(0); k <= n; k++ {
+		for _, i := range [2]int64{l - k, l + k} {
+			if c, ok := e.Children[i]; ok && c != nil && i >= 0 {
+				r = c.Find(data, n, m, r)
+			}
+			if k == 0 {
+				break
+			}
 		}
 	}
 	return r
 }
+
+func (e *node) search(q *query) bool {
+	if !q.next() {
+		return false
+	}
+	l := int64(q.metric(e.Data, q.data))
+	if q.min <= l && l <= q.max && !q.match(Match{e.value(), l}) {
+		return false
+	}
+	// Every word under child i lies at distance i from e, and so at a distance
+	// from data between |l-i| and l+i. Children are visited by increasing
+	// |l-i|, so that the most promising subtrees come first; words under
+	// children below q.min-l are too close to data.
+	q.pruned += len(e.Children)
+	return e.near(l, max(l-q.max, q.min-l, 0), l+q.max, func(c *node) bool {
+		q.pruned--
+		return c.search(q)
+	})
+}
+
+func (e *node) searchFloat(q *query) bool {
+	if !q.next() {
+		return false
+	}
+	d := q.float(e.Data, q.data)
+	if q.rmin <= d && d <= q.rmax && !q.match(Match{e.value(), int64(math.Floor(d / q.resolution))}) {
+		return false
+	}
+	// A child under bucket i lies at a real distance in [i*res, (i+1)*res) from e,
+	// so only buckets overlapping [d-rmax, d+rmax] and reaching rmin-d can hold
+	// matches. Buckets are visited outwards from the bucket of d.
+	lo := max(int64(math.Floor(max(d-q.rmax, q.rmin-d)/q.resolution)), 0)
+	hi := int64(math.Floor((d + q.rmax) / q.resolution))
+	center := int64(math.Floor(d / q.resolution))
+	q.pruned += len(e.Children)
+	return e.near(center, lo, hi, func(c *node) bool {
+		q.pruned--
+		return c.searchFloat(q)
+	})
+}
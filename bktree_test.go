@@ -2,8 +2,12 @@
 package bktree
 
 import (
+	"context"
+	"math"
 	"math/rand"
 	"os"
+	"path/filepath"
+	"strconv"
 	"testing"
 
 	"github.com/agnivade/levenshtein"
@@ -20,6 +24,31 @@ func TestFile(t *testing.T) {
 
 }
 
+func TestAddAfterRead(t *testing.T) {
+	// Leaves are stored without children, and read back with a nil map.
+	bk := New(levenshteinFromBytes)
+	bk.Add([]byte("book"))
+	bk.Add([]byte("books"))
+	filePath := filepath.Join(t.TempDir(), "tree.db")
+	if _, err := bk.SaveToFile(filePath); err != nil {
+		t.Fatal(err)
+	}
+	loaded := New(levenshteinFromBytes)
+	if err := loaded.ReadFromFile(filePath); err != nil {
+		t.Fatal(err)
+	}
+	loaded.Add([]byte("boo")) // Under books, at distance 2
+	if r := loaded.Find([]byte("boo"), 0); len(r) != 1 {
+		t.Fatalf("Find(boo) after adding it under a leaf read from file found %q", r)
+	}
+
+	root := &Node{Data: []byte("book")}
+	root.Add([]byte("books"), levenshteinFromBytes)
+	if r := root.Find([]byte("books"), 0, levenshteinFromBytes, [][]byte{}); len(r) != 1 {
+		t.Fatalf("Find(books) under a node without a map found %q", r)
+	}
+}
+
 func testFileRead(t *testing.T, dict []string, filePath string) {
 
 	defer os.Remove(filePath)
@@ -68,6 +97,442 @@ func TestFindLg(t *testing.T) {
 	testFind(t, dictLg)
 }
 
+func TestFindRange(t *testing.T) {
+	bk := New(levenshteinFromBytes)
+	for _, w := range dictLg {
+		bk.Add([]byte(w))
+	}
+
+	for i := 0; i < 50; i++ {
+		q := mess(pick(dictLg), 2)
+		min, max := int64(rand.Intn(3)), int64(rand.Intn(3)+2)
+
+		want := map[string]int{}
+		for _, w := range dictLg {
+			if d := int64(levenshtein.ComputeDistance(q, w)); min <= d && d <= max {
+				want[w]++
+			}
+		}
+		for _, w := range bk.FindRange([]byte(q), min, max) {
+			want[string(w)]--
+		}
+		for w, n := range want {
+			if n != 0 {
+				t.Fatalf("FindRange(%s, %d, %d) returned %s %d times too few", q, min, max, w, n)
+			}
+		}
+	}
+}
+
+func TestFindFunc(t *testing.T) {
+	calls := 0
+	bk := New(func(a, b []byte) int {
+		calls++
+		return levenshteinFromBytes(a, b)
+	})
+	for _, w := range dictLg {
+		bk.Add([]byte(w))
+	}
+
+	calls = 0
+	all := bk.Find([]byte("mission"), 4)
+	full := calls
+
+	calls = 0
+	found := 0
+	bk.FindFunc([]byte("mission"), 4, func(m Match) bool {
+		if d := int64(levenshteinFromBytes([]byte("mission"), m.Data)); d != m.Distance {
+			t.Fatalf("match %s reported at distance %d, want %d", m.Data, m.Distance, d)
+		}
+		found++
+		return false
+	})
+	if found != 1 || len(all) < 2 {
+		t.Fatalf("FindFunc called back %d times, Find found %d words", found, len(all))
+	}
+	if calls >= full {
+		t.Fatalf("FindFunc evaluated the metric %d times, Find %d times", calls, full)
+	}
+
+	n := 0
+	for w, d := range bk.Search([]byte("mission"), 4) {
+		if d > 4 || levenshteinFromBytes([]byte("mission"), w) != int(d) {
+			t.Fatalf("Search yielded %s at distance %d", w, d)
+		}
+		n++
+		if n == 2 {
+			break
+		}
+	}
+	if n != 2 {
+		t.Fatalf("Search yielded %d words before break, want 2", n)
+	}
+}
+
+func TestFindWithOptions(t *testing.T) {
+	calls := 0
+	bk := New(func(a, b []byte) int {
+		calls++
+		return levenshteinFromBytes(a, b)
+	})
+	bk.AddAll(wordsOf(dictLg))
+
+	all := bk.Find([]byte("mission"), 5)
+	r, truncated := bk.FindWithOptions([]byte("mission"), 5, FindOptions{})
+	if truncated || len(r) != len(all) {
+		t.Fatalf("unlimited search found %d of %d words, truncated: %v", len(r), len(all), truncated)
+	}
+
+	r, truncated = bk.FindWithOptions([]byte("mission"), 5, FindOptions{MaxResults: 3})
+	if !truncated || len(r) != 3 {
+		t.Fatalf("search limited to 3 results found %d words, truncated: %v", len(r), truncated)
+	}
+
+	calls = 0
+	r, truncated = bk.FindWithOptions([]byte("mission"), 5, FindOptions{MaxMetricCalls: 50})
+	if !truncated || calls != 50 || len(r) >= len(all) {
+		t.Fatalf("search limited to 50 metric calls made %d and found %d words, truncated: %v", calls, len(r), truncated)
+	}
+
+	calls = 0
+	_, truncated = bk.FindWithOptions([]byte("mission"), 5, FindOptions{MaxVisited: 20})
+	if !truncated || calls != 20 {
+		t.Fatalf("search limited to 20 nodes made %d metric calls, truncated: %v", calls, truncated)
+	}
+
+	for _, w := range r {
+		if levenshtein.ComputeDistance("mission", string(w)) > 5 {
+			t.Fatalf("truncated search returned %s", w)
+		}
+	}
+}
+
+func TestLen(t *testing.T) {
+	bk := New(levenshteinFromBytes)
+	if bk.Len() != 0 {
+		t.Fatalf("empty tree has length %d", bk.Len())
+	}
+	bk.AddAll(wordsOf(dictSm))
+	bk.Add([]byte(dictSm[0]))
+	if bk.Len() != len(dictSm)+1 {
+		t.Fatalf("tree has length %d, want %d", bk.Len(), len(dictSm)+1)
+	}
+
+	filePath := filepath.Join(t.TempDir(), "tree.db")
+	if _, err := bk.SaveToFile(filePath); err != nil {
+		t.Fatal(err)
+	}
+	loaded := New(levenshteinFromBytes)
+	if err := loaded.ReadFromFile(filePath); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Len() != bk.Len() {
+		t.Fatalf("tree read from file has length %d, want %d", loaded.Len(), bk.Len())
+	}
+
+	bk.Clear()
+	if bk.Len() != 0 || len(bk.Find([]byte(dictSm[0]), 2)) != 0 || bk.Contains([]byte(dictSm[0])) {
+		t.Fatal("cleared tree is not empty")
+	}
+	bk.Add([]byte("again"))
+	if bk.Len() != 1 {
+		t.Fatalf("tree has length %d after Clear and Add, want 1", bk.Len())
+	}
+}
+
+func TestWalk(t *testing.T) {
+	bk := New(levenshteinFromBytes)
+	for _, w := range []string{"book", "books", "cake", "boo", "cape", "cart"} {
+		bk.Add([]byte(w))
+	}
+
+	type visit struct {
+		w     string
+		depth int
+	}
+	r := []visit{}
+	bk.Walk(func(data []byte, depth int) bool {
+		r = append(r, visit{string(data), depth})
+		return true
+	})
+	// books and boo sit at distance 1 from book, boo under books; cake at 4,
+	// cape under cake at 1, cart under cake at 2.
+	want := []visit{{"book", 0}, {"books", 1}, {"boo", 2}, {"cake", 1}, {"cape", 2}, {"cart", 2}}
+	if len(r) != len(want) {
+		t.Fatalf("Walk visited %v, want %v", r, want)
+	}
+	for i := range r {
+		if r[i] != want[i] {
+			t.Fatalf("Walk visited %v, want %v", r, want)
+		}
+	}
+
+	n := 0
+	bk.Walk(func([]byte, int) bool {
+		n++
+		return n < 3
+	})
+	if n != 3 {
+		t.Fatalf("Walk went on for %d words after being stopped at 3", n)
+	}
+}
+
+func TestFindContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	calls, cancelAt := 0, -1
+	bk := New(func(a, b []byte) int {
+		calls++
+		if calls == cancelAt {
+			cancel()
+		}
+		return levenshteinFromBytes(a, b)
+	})
+	if err := bk.AddAllContext(ctx, wordsOf(dictLg)); err != nil {
+		t.Fatal(err)
+	}
+
+	calls = 0
+	all, err := bk.FindContext(context.Background(), []byte("mission"), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	full := calls
+
+	calls, cancelAt = 0, 100
+	r, err := bk.FindContext(ctx, []byte("mission"), 10)
+	if err != context.Canceled {
+		t.Fatalf("FindContext returned error %v, want %v", err, context.Canceled)
+	}
+	if len(r) >= len(all) || calls >= full {
+		t.Fatalf("cancelled search found %d of %d words with %d of %d metric calls", len(r), len(all), calls, full)
+	}
+
+	if err := bk.AddAllContext(ctx, wordsOf(dictSm)); err != context.Canceled {
+		t.Fatalf("AddAllContext returned error %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestSaveToFileContext(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "tree.db")
+	bk := New(levenshteinFromBytes)
+	bk.AddAll(wordsOf(dictSm))
+	if saved, err := bk.SaveToFile(filePath); !saved || err != nil {
+		t.Fatalf("SaveToFile returned %v, %v", saved, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	bk.Add([]byte("extra"))
+	if saved, err := bk.SaveToFileContext(ctx, filePath); saved || err != context.Canceled {
+		t.Fatalf("SaveToFileContext returned %v, %v", saved, err)
+	}
+
+	loaded := New(levenshteinFromBytes)
+	if err := loaded.ReadFromFile(filePath); err != nil {
+		t.Fatal(err)
+	}
+	if r := loaded.Find([]byte("extra"), 0); len(r) != 0 {
+		t.Fatal("cancelled save overwrote the previous file")
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(filePath)); len(entries) != 1 {
+		t.Fatalf("cancelled save left %d files behind", len(entries))
+	}
+
+	// SaveToFile writes through a symbolic link, SaveToFileContext replaces it.
+	link := filepath.Join(filepath.Dir(filePath), "link.db")
+	if err := os.Symlink(filePath, link); err != nil {
+		t.Skip(err)
+	}
+	if _, err := bk.SaveToFile(link); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Lstat(link); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("SaveToFile replaced the link: %v", err)
+	}
+	if err := loaded.ReadFromFile(filePath); err != nil || len(loaded.Find([]byte("extra"), 0)) != 1 {
+		t.Fatalf("SaveToFile did not write to the target of the link: %v", err)
+	}
+	if _, err := bk.SaveToFileContext(context.Background(), link); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Lstat(link); err != nil || !fi.Mode().IsRegular() {
+		t.Fatalf("SaveToFileContext kept the link: %v", err)
+	}
+}
+
+func wordsOf(dict []string) [][]byte {
+	r := make([][]byte, len(dict))
+	for i, w := range dict {
+		r[i] = []byte(w)
+	}
+	return r
+}
+
+func TestAll(t *testing.T) {
+	bk := New(levenshteinFromBytes)
+	for range bk.All() {
+		t.Fatal("empty tree yielded a word")
+	}
+
+	want := map[string]int{}
+	for _, w := range dictSm {
+		bk.Add([]byte(w))
+		want[w]++
+	}
+	for w := range bk.All() {
+		want[string(w)]--
+	}
+	for w, n := range want {
+		if n != 0 {
+			t.Fatalf("All yielded %s %d times too few", w, n)
+		}
+	}
+}
+
+func TestContains(t *testing.T) {
+	calls := 0
+	counting := func(a, b []byte) int {
+		calls++
+		return levenshteinFromBytes(a, b)
+	}
+	for _, exact := range []bool{false, true} {
+		bk := New(counting)
+		bk.ExactIndex = exact
+		if bk.Contains([]byte("bolivia")) {
+			t.Fatal("empty tree contains bolivia")
+		}
+		bk.AddAll(wordsOf(dictSm))
+		bk.Add([]byte("bolivia"))
+
+		check := func(bk *BKTree) {
+			t.Helper()
+			calls = 0
+			if !bk.Contains([]byte("bolivia")) || bk.Contains([]byte("bolivian")) {
+				t.Fatalf("ExactIndex %v: Contains disagrees with the added words", exact)
+			}
+			if exact && calls != 0 {
+				t.Fatalf("Contains with an index made %d metric calls", calls)
+			}
+			if r, ok := bk.Get([]byte("bolivia")); !ok || len(r) != 2 {
+				t.Fatalf("ExactIndex %v: Get(bolivia) = %q, %v, want two copies", exact, r, ok)
+			}
+			if r, ok := bk.Get([]byte("bolivian")); ok || len(r) != 0 {
+				t.Fatalf("ExactIndex %v: Get(bolivian) = %q, %v", exact, r, ok)
+			}
+		}
+		check(bk)
+
+		filePath := filepath.Join(t.TempDir(), "tree.db")
+		if _, err := bk.SaveToFile(filePath); err != nil {
+			t.Fatal(err)
+		}
+		loaded := New(counting)
+		loaded.ExactIndex = exact
+		if err := loaded.ReadFromFile(filePath); err != nil {
+			t.Fatal(err)
+		}
+		check(loaded)
+
+		loaded.AddAll(wordsOf(dictLg))
+		for _, w := range dictLg {
+			if !loaded.Contains([]byte(w)) {
+				t.Fatalf("ExactIndex %v: tree read from file does not contain %s added afterwards", exact, w)
+			}
+		}
+	}
+
+	// An index requested later covers the words already added.
+	bk := New(counting)
+	bk.AddAll(wordsOf(dictSm))
+	bk.ExactIndex = true
+	bk.Add([]byte("bolivia"))
+	calls = 0
+	if r, ok := bk.Get([]byte(dictSm[0])); !ok || len(r) != 1 || calls != 0 {
+		t.Fatalf("Get(%s) with a late index = %q, %v after %d metric calls", dictSm[0], r, ok, calls)
+	}
+
+	bk = New(levenshteinFromBytes)
+	bk.Normalizer = FoldCase
+	bk.Add([]byte("Bolivia"))
+	if r, ok := bk.Get([]byte("BOLIVIA")); !ok || len(r) != 1 || string(r[0]) != "Bolivia" {
+		t.Fatalf("Get(BOLIVIA) = %q, %v", r, ok)
+	}
+
+	// Changing the returned slice leaves the tree untouched.
+	r, _ := bk.Get([]byte("bolivia"))
+	r[0] = []byte("Peru")
+	_ = append(r[:0], []byte("Chile"))
+	if r, _ := bk.Get([]byte("bolivia")); len(r) != 1 || string(r[0]) != "Bolivia" {
+		t.Fatalf("Get(bolivia) after changing its result = %q", r)
+	}
+}
+
+func TestFindFloat(t *testing.T) {
+	words := [][]byte{}
+	for i := 0; i < 500; i++ {
+		words = append(words, []byte(strconv.FormatFloat(rand.Float64()*100, 'f', -1, 64)))
+	}
+
+	bk := NewFloat(absDiff, 0.7)
+	for _, w := range words {
+		bk.Add(w)
+	}
+
+	for i := 0; i < 100; i++ {
+		q := []byte(strconv.FormatFloat(rand.Float64()*100, 'f', -1, 64))
+		r := rand.Float64() * 3
+
+		want := 0
+		for _, w := range words {
+			if absDiff(q, w) <= r {
+				want++
+			}
+		}
+		found := bk.FindFloat(q, r)
+		if len(found) != want {
+			t.Fatalf("FindFloat(%s, %f) found %d words, want %d", q, r, len(found), want)
+		}
+
+		want = 0
+		for _, w := range words {
+			if d := absDiff(q, w); 0.7 <= d && d <= 2.1 {
+				want++
+			}
+		}
+		if n := len(bk.FindRange(q, 1, 3)); n != want {
+			t.Fatalf("FindRange(%s, 1, 3) found %d words, want %d", q, n, want)
+		}
+		for _, w := range found {
+			if absDiff(q, w) > r {
+				t.Fatalf("FindFloat(%s, %f) returned %s", q, r, w)
+			}
+		}
+	}
+}
+
+func TestFindFloatInteger(t *testing.T) {
+	bk := New(levenshteinFromBytes)
+	bk.AddAll(wordsOf(dictSm))
+
+	// Trees with an integer metric are searched within floor(r).
+	for _, w := range dictSm[:20] {
+		q := []byte(mess(w, 2))
+		if r, want := bk.FindFloat(q, 2.9), bk.Find(q, 2); len(r) != len(want) {
+			t.Fatalf("FindFloat(%s, 2.9) found %d words, want %d", q, len(r), len(want))
+		}
+	}
+	for _, r := range []float64{-0.5, math.NaN()} {
+		if found := bk.FindFloat([]byte(dictSm[0]), r); len(found) != 0 {
+			t.Fatalf("FindFloat(%s, %v) found %d words", dictSm[0], r, len(found))
+		}
+	}
+	if n := len(bk.FindFloat([]byte(dictSm[0]), math.Inf(1))); n != bk.Len() {
+		t.Fatalf("FindFloat(%s, +Inf) found %d of %d words", dictSm[0], n, bk.Len())
+	}
+}
+
 func BenchmarkFindSm(b *testing.B) {
 	benchmarkFind(b, dictSm)
 }
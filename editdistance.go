@@ -0,0 +1,138 @@
+package bktree
+
+// EditCosts describes the operation costs of a weighted edit distance. Entries
+// missing from the tables cost Default, or 1 if Default is zero.
+//
+// Substitute is keyed by {from, to} and either side may span several runes,
+// such as {"rn", "m"} for a common OCR confusion. Substitutions apply in both
+// directions; a missing reverse entry takes the cost of the forward one.
+type EditCosts struct {
+	Insert     map[rune]int
+	Delete     map[rune]int
+	Substitute map[[2]string]int
+	Default    int
+}
+
+// WeightedEdit returns a Metric computing the cheapest sequence of insertions,
+// deletions and substitutions turning one UTF-8 string into the other.
+//
+// The result is a metric whenever all costs are positive, Insert and Delete
+// agree for every rune, and the single-rune substitution costs together with
+// the insertion costs satisfy the triangle inequality, e.g. substituting a for
+// b never costs more than deleting a and inserting b. Multi-rune substitutions
+// are applied as alignment steps and should be checked with ValidateMetric.
+func WeightedEdit(c EditCosts) Metric {
+	def := c.Default
+	if def == 0 {
+		def = 1
+	}
+
+	single := map[[2]rune]int{}
+	multi := []substitution{}
+	add := func(from, to string, cost int) {
+		f, t := []rune(from), []rune(to)
+		if len(f) == 1 && len(t) == 1 {
+			if _, ok := single[[2]rune{f[0], t[0]}]; !ok {
+				single[[2]rune{f[0], t[0]}] = cost
+			}
+			return
+		}
+		for _, s := range multi {
+			if string(s.from) == from && string(s.to) == to {
+				return
+			}
+		}
+		multi = append(multi, substitution{f, t, cost})
+	}
+	for k, cost := range c.Substitute {
+		add(k[0], k[1], cost)
+	}
+	for k, cost := range c.Substitute {
+		add(k[1], k[0], cost)
+	}
+
+	cost := func(table map[rune]int, r rune) int {
+		if v, ok := table[r]; ok {
+			return v
+		}
+		return def
+	}
+
+	return func(a, b []byte) int {
+		x, y := []rune(string(a)), []rune(string(b))
+		w := len(y) + 1
+		d := make([]int, (len(x)+1)*w)
+		for j := 1; j <= len(y); j++ {
+			d[j] = d[j-1] + cost(c.Insert, y[j-1])
+		}
+		for i := 1; i <= len(x); i++ {
+			d[i*w] = d[(i-1)*w] + cost(c.Delete, x[i-1])
+			for j := 1; j <= len(y); j++ {
+				best := d[(i-1)*w+j] + cost(c.Delete, x[i-1])
+				best = min(best, d[i*w+j-1]+cost(c.Insert, y[j-1]))
+
+				sub := 0
+				if x[i-1] != y[j-1] {
+					var ok bool
+					if sub, ok = single[[2]rune{x[i-1], y[j-1]}]; !ok {
+						sub = def
+					}
+				}
+				best = min(best, d[(i-1)*w+j-1]+sub)
+
+				for _, s := range multi {
+					if hasRuneSuffix(x[:i], s.from) && hasRuneSuffix(y[:j], s.to) {
+						best = min(best, d[(i-len(s.from))*w+j-len(s.to)]+s.cost)
+					}
+				}
+				d[i*w+j] = best
+			}
+		}
+		return d[len(d)-1]
+	}
+}
+
+type substitution struct {
+	from, to []rune
+	cost     int
+}
+
+func hasRuneSuffix(s, suffix []rune) bool {
+	if len(suffix) > len(s) {
+		return false
+	}
+	s = s[len(s)-len(suffix):]
+	for i := range s {
+		if s[i] != suffix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// QWERTYAdjacent returns a substitution table charging cost for replacing a
+// lower-case letter with one of its neighbours on a QWERTY keyboard.
+func QWERTYAdjacent(cost int) map[[2]string]int {
+	rows := []string{"qwertyuiop", "asdfghjkl", "zxcvbnm"}
+	at := func(r, c int) (string, bool) {
+		if r < 0 || r >= len(rows) || c < 0 || c >= len(rows[r]) {
+			return "", false
+		}
+		return rows[r][c : c+1], true
+	}
+
+	t := map[[2]string]int{}
+	for r, row := range rows {
+		for c := range row {
+			k := row[c : c+1]
+			// Rows are staggered, so a key touches the keys at the same and the next
+			// index in the row above, and the same and the previous index in the row below.
+			for _, n := range [][2]int{{r, c - 1}, {r, c + 1}, {r - 1, c}, {r - 1, c + 1}, {r + 1, c}, {r + 1, c - 1}} {
+				if a, ok := at(n[0], n[1]); ok {
+					t[[2]string{k, a}] = cost
+				}
+			}
+		}
+	}
+	return t
+}
@@ -0,0 +1,65 @@
+package bktree
+
+import "testing"
+
+func TestWeightedEditUniform(t *testing.T) {
+	m := WeightedEdit(EditCosts{})
+	for _, a := range dictSm[:20] {
+		for _, b := range dictSm[:20] {
+			if d, want := m([]byte(a), []byte(b)), levenshteinFromBytes([]byte(a), []byte(b)); d != want {
+				t.Fatalf("WeightedEdit(%s, %s) = %d, want %d", a, b, d, want)
+			}
+		}
+	}
+}
+
+func TestWeightedEditCosts(t *testing.T) {
+	m := WeightedEdit(EditCosts{
+		Insert:     map[rune]int{'e': 2},
+		Delete:     map[rune]int{'e': 2},
+		Substitute: map[[2]string]int{{"rn", "m"}: 1, {"é", "e"}: 1},
+		Default:    3,
+	})
+	cases := []struct {
+		a, b string
+		d    int
+	}{
+		{"corn", "com", 1},
+		{"com", "corn", 1},
+		{"café", "cafe", 1},
+		{"cafe", "caf", 2},
+		{"cat", "cot", 3},
+	}
+	for _, c := range cases {
+		if d := m([]byte(c.a), []byte(c.b)); d != c.d {
+			t.Errorf("WeightedEdit(%s, %s) = %d, want %d", c.a, c.b, d, c.d)
+		}
+	}
+}
+
+func TestQWERTYAdjacent(t *testing.T) {
+	m := WeightedEdit(EditCosts{Substitute: QWERTYAdjacent(1), Default: 2})
+	if d := m([]byte("cat"), []byte("cst")); d != 1 {
+		t.Errorf("adjacent substitution costs %d, want 1", d)
+	}
+	if d := m([]byte("cat"), []byte("cpt")); d != 2 {
+		t.Errorf("distant substitution costs %d, want 2", d)
+	}
+
+	samples := [][]byte{}
+	for _, w := range dictSm[:15] {
+		samples = append(samples, []byte(w))
+	}
+	if v := ValidateMetric(m, samples); len(v) != 0 {
+		t.Fatalf("unexpected violations: %v", v)
+	}
+
+	bk := New(m)
+	for _, w := range dictSm {
+		bk.Add([]byte(w))
+	}
+	r := bk.Find([]byte("rist"), 1)
+	if len(r) != 1 || string(r[0]) != "rust" {
+		t.Fatalf("found %q, want [rust]", r)
+	}
+}
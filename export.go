@@ -0,0 +1,107 @@
+package bktree
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DOTOptions limits the part of a tree rendered by ExportDOT.
+type DOTOptions struct {
+	MaxDepth int // Deepest level rendered, the root being at depth 0; unlimited if zero
+	MaxNodes int // Maximum number of nodes rendered; unlimited if zero
+}
+
+// ExportDOT renders the tree in the Graphviz DOT language, with nodes labeled
+// by the words originally added and edges by their distance. Nodes are emitted level by level
+// in order of distance, so that a size limit keeps the upper levels. Subtrees
+// left out by the limits are drawn as a single node counting their words.
+func (t *BKTree) ExportDOT(w io.Writer, opts DOTOptions) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintln(b, "digraph bktree {")
+	fmt.Fprintln(b, "\tnode [shape=box];")
+
+	type item struct {
+		e     *node
+		depth int
+	}
+	queue := []item{}
+	ids := 0
+	if root := t.load().root; root != nil {
+		queue = append(queue, item{root, 0})
+	}
+	for len(queue) > 0 {
+		it := queue[0]
+		queue = queue[1:]
+		id := ids
+		ids++
+		fmt.Fprintf(b, "\tn%d [label=%s];\n", id, dotQuote(it.e.value()))
+
+		hidden := 0
+		for _, c := range it.e.Children {
+			if c.Node == nil {
+				continue
+			}
+			if opts.MaxDepth > 0 && it.depth >= opts.MaxDepth || opts.MaxNodes > 0 && ids+len(queue) >= opts.MaxNodes {
+				hidden += c.Node.count()
+				continue
+			}
+			fmt.Fprintf(b, "\tn%d -> n%d [label=\"%d\"];\n", id, ids+len(queue), c.Dist)
+			queue = append(queue, item{c.Node, it.depth + 1})
+		}
+		if hidden > 0 {
+			fmt.Fprintf(b, "\tn%dmore [label=\"%d more\", shape=plaintext];\n", id, hidden)
+			fmt.Fprintf(b, "\tn%d -> n%dmore [style=dashed];\n", id, id)
+		}
+	}
+
+	fmt.Fprintln(b, "}")
+	return b.Flush()
+}
+
+// dotEscaper escapes the characters DOT treats specially in quoted strings.
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// dotQuote returns data as a DOT quoted string.
+func dotQuote(data []byte) string {
+	return `"` + dotEscaper.Replace(string(data)) + `"`
+}
+
+// count returns the number of words in the subtree rooted at e.
+func (e *node) count() int {
+	n := 0
+	e.walk(func(*node, int) bool {
+		n++
+		return true
+	}, 0)
+	return n
+}
+
+// ExportJSON writes the structure of the tree as JSON. Nodes carry the fields
+// of the protobuf message: data and value in base64, and children keyed by
+// distance. An empty tree is written as null.
+func (t *BKTree) ExportJSON(w io.Writer) error {
+	var root *Node
+	if c := t.load(); c.root != nil {
+		root = c.root.toProto()
+	}
+	return json.NewEncoder(w).Encode(root)
+}
+
+// ImportJSON replaces the contents of the tree with a structure written by
+// ExportJSON. The tree must use the metric the structure was built with.
+func (t *BKTree) ImportJSON(r io.Reader) error {
+	var root *Node
+	if err := json.NewDecoder(r).Decode(&root); err != nil {
+		return err
+	}
+	if root == nil {
+		t.replace(nil)
+	} else {
+		t.replace(indexed(fromProto(root), t.ExactIndex))
+	}
+	t.dirty = true
+	return nil
+}
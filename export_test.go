@@ -0,0 +1,120 @@
+package bktree
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+func TestExportDOT(t *testing.T) {
+	bk := New(levenshteinFromBytes)
+	bk.AddAll(wordsOf([]string{"book", "books", "cake", "boo", "cape", "cart"}))
+
+	var b bytes.Buffer
+	if err := bk.ExportDOT(&b, DOTOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	want := `digraph bktree {
+	node [shape=box];
+	n0 [label="book"];
+	n0 -> n1 [label="1"];
+	n0 -> n2 [label="4"];
+	n1 [label="books"];
+	n1 -> n3 [label="2"];
+	n2 [label="cake"];
+	n2 -> n4 [label="1"];
+	n2 -> n5 [label="2"];
+	n3 [label="boo"];
+	n4 [label="cape"];
+	n5 [label="cart"];
+}
+`
+	if b.String() != want {
+		t.Fatalf("ExportDOT wrote\n%s\nwant\n%s", b.String(), want)
+	}
+
+	b.Reset()
+	if err := bk.ExportDOT(&b, DOTOptions{MaxDepth: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if s := b.String(); strings.Contains(s, "cape") || !strings.Contains(s, `n2more [label="2 more", shape=plaintext]`) {
+		t.Fatalf("ExportDOT limited to depth 1 wrote\n%s", s)
+	}
+
+	b.Reset()
+	if err := bk.ExportDOT(&b, DOTOptions{MaxNodes: 4}); err != nil {
+		t.Fatal(err)
+	}
+	if s := b.String(); strings.Count(s, "[label=\"") != 8 || !strings.Contains(s, `n2more [label="2 more"`) {
+		t.Fatalf("ExportDOT limited to 4 nodes wrote\n%s", s)
+	}
+
+	b.Reset()
+	if err := New(levenshteinFromBytes).ExportDOT(&b, DOTOptions{}); err != nil || b.String() != "digraph bktree {\n\tnode [shape=box];\n}\n" {
+		t.Fatalf("ExportDOT of an empty tree wrote %q, %v", b.String(), err)
+	}
+	// Labels show the words added, not the keys they are indexed by.
+	tokens := NewTokenTree(Words)
+	tokens.Add([]byte("red shirt"))
+	tokens.Add([]byte(`say "hi" \ bye`))
+	b.Reset()
+	if err := tokens.ExportDOT(&b, DOTOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if s := b.String(); !strings.Contains(s, `n0 [label="red shirt"]`) || !strings.Contains(s, `n1 [label="say \"hi\" \\ bye"]`) {
+		t.Fatalf("ExportDOT of a token tree wrote\n%s", s)
+	}
+
+	// Nil children, only found in corrupt files, are left out.
+	bk.load().root.Children = append(bk.load().root.Children, edge{9, nil})
+	b.Reset()
+	if err := bk.ExportDOT(&b, DOTOptions{MaxDepth: 1}); err != nil || strings.Contains(b.String(), `label="9"`) {
+		t.Fatalf("ExportDOT with a nil child wrote\n%s, %v", b.String(), err)
+	}
+}
+
+func TestExportJSON(t *testing.T) {
+	bk := New(levenshteinFromBytes)
+	bk.Normalizer = FoldCase
+	bk.AddAll(wordsOf(dictSm))
+	bk.Add([]byte("Book"))
+
+	var b bytes.Buffer
+	if err := bk.ExportJSON(&b); err != nil {
+		t.Fatal(err)
+	}
+	imported := New(levenshteinFromBytes)
+	if err := imported.ImportJSON(&b); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(imported.load().root.toProto(), bk.load().root.toProto()) || imported.Len() != bk.Len() {
+		t.Fatal("tree does not round-trip through JSON")
+	}
+	if err := imported.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The imported tree reads back from protobuf identical to the original.
+	dir := t.TempDir()
+	if _, err := imported.SaveToFile(filepath.Join(dir, "tree.db")); err != nil {
+		t.Fatal(err)
+	}
+	loaded := New(levenshteinFromBytes)
+	if err := loaded.ReadFromFile(filepath.Join(dir, "tree.db")); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(loaded.load().root.toProto(), bk.load().root.toProto()) {
+		t.Fatal("tree does not round-trip through JSON and protobuf")
+	}
+
+	b.Reset()
+	if err := New(levenshteinFromBytes).ExportJSON(&b); err != nil || b.String() != "null\n" {
+		t.Fatalf("ExportJSON of an empty tree wrote %q, %v", b.String(), err)
+	}
+	if err := imported.ImportJSON(&b); err != nil || imported.Len() != 0 {
+		t.Fatalf("importing an empty tree left %d words, %v", imported.Len(), err)
+	}
+}
@@ -0,0 +1,168 @@
+package bktree
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Partition returns the shard, between 0 and shards-1, a word is added to.
+type Partition func(data []byte, shards int) int
+
+// HashPartition spreads words evenly over the shards by hashing them.
+func HashPartition(data []byte, shards int) int {
+	h := fnv.New32a()
+	h.Write(data)
+	return int(h.Sum32() % uint32(shards))
+}
+
+// LengthPartition places words of lengths [k*width, (k+1)*width) in shard k,
+// the longest words all going to the last shard. It panics if width is not
+// positive.
+func LengthPartition(width int) Partition {
+	if width < 1 {
+		panic("bktree: partition width must be positive")
+	}
+	return func(data []byte, shards int) int {
+		return min(len(data)/width, shards-1)
+	}
+}
+
+// PivotPartition places words in shard k when pivots[k] is the closest pivot
+// to them, so that each shard gathers similar words. There should be one
+// pivot per shard; ties go to the first closest pivot. PivotPartition panics
+// if pivots is empty.
+func PivotPartition(pivots [][]byte, m Metric) Partition {
+	if len(pivots) == 0 {
+		panic("bktree: no pivots to partition by")
+	}
+	return func(data []byte, shards int) int {
+		best, bestDist := 0, m(pivots[0], data)
+		for k, p := range pivots[1:min(len(pivots), shards)] {
+			if d := m(p, data); d < bestDist {
+				best, bestDist = k+1, d
+			}
+		}
+		return best
+	}
+}
+
+// Forest is an index made of independent BK-trees, or shards, among which
+// words are partitioned. Shards are searched in parallel, and saved to and
+// read from separate files.
+type Forest struct {
+	shards    []*BKTree
+	partition Partition
+}
+
+// NewForest returns a forest of n empty shards over the metric m. Settings
+// such as a Normalizer are made on each shard, through Shard. NewForest panics
+// if n is less than one.
+func NewForest(n int, m Metric, p Partition) *Forest {
+	if n < 1 {
+		panic("bktree: a forest needs at least one shard")
+	}
+	f := &Forest{shards: make([]*BKTree, n), partition: p}
+	for i := range f.shards {
+		f.shards[i] = New(m)
+	}
+	return f
+}
+
+// Shards returns the number of shards of the forest.
+func (f *Forest) Shards() int {
+	return len(f.shards)
+}
+
+// Shard returns the i-th shard of the forest, which may be searched, loaded
+// or rebuilt on its own. Words added to it directly must belong to it under
+// the partition of the forest.
+func (f *Forest) Shard(i int) *BKTree {
+	return f.shards[i]
+}
+
+// Add inserts a new word into the shard given by the partition.
+func (f *Forest) Add(data []byte) {
+	f.shards[f.partition(data, len(f.shards))].Add(data)
+}
+
+// AddAll inserts every word of data into the forest.
+func (f *Forest) AddAll(data [][]byte) {
+	for _, w := range data {
+		f.Add(w)
+	}
+}
+
+// Len returns the number of words in the forest.
+func (f *Forest) Len() int {
+	n := 0
+	for _, t := range f.shards {
+		n += t.Len()
+	}
+	return n
+}
+
+// Find returns the words within distance n of data, searching every shard
+// in its own goroutine. Results are grouped by shard, in shard order.
+func (f *Forest) Find(data []byte, n int64) [][]byte {
+	parts := make([][][]byte, len(f.shards))
+	var wg sync.WaitGroup
+	for i, t := range f.shards {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			parts[i] = t.Find(data, n)
+		}()
+	}
+	wg.Wait()
+
+	r := [][]byte{}
+	for _, p := range parts {
+		r = append(r, p...)
+	}
+	return r
+}
+
+// ShardPath returns the file under dir holding the i-th shard of a forest.
+func ShardPath(dir string, i int) string {
+	return filepath.Join(dir, fmt.Sprintf("shard%d.db", i))
+}
+
+// SaveToDir saves every shard to its file under dir, as given by ShardPath.
+// The files of empty shards are removed.
+func (f *Forest) SaveToDir(dir string) error {
+	for i, t := range f.shards {
+		path := ShardPath(dir, i)
+		if t.Len() == 0 {
+			if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+			continue
+		}
+		if _, err := t.SaveToFile(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadFromDir reads every shard from its file under dir, as given by
+// ShardPath. Shards without a file are left empty. The forest must have as
+// many shards, and the same partition, as the forest saved: the files of
+// further shards are not read, and words added afterwards would not go to the
+// shards holding the words read.
+func (f *Forest) ReadFromDir(dir string) error {
+	for i, t := range f.shards {
+		err := t.ReadFromFile(ShardPath(dir, i))
+		if errors.Is(err, fs.ErrNotExist) {
+			t.Clear()
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package bktree
+
+import (
+	"bytes"
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestForest(t *testing.T) {
+	bk := New(levenshteinFromBytes)
+	bk.AddAll(wordsOf(dictLg))
+
+	partitions := map[string]Partition{
+		"hash":   HashPartition,
+		"length": LengthPartition(3),
+		"pivot":  PivotPartition(wordsOf([]string{"mission", "abstract", "zoo", "kinetic"}), levenshteinFromBytes),
+	}
+	for name, p := range partitions {
+		f := NewForest(4, levenshteinFromBytes, p)
+		f.AddAll(wordsOf(dictLg))
+		if f.Len() != len(dictLg) {
+			t.Fatalf("%s: forest holds %d words, want %d", name, f.Len(), len(dictLg))
+		}
+		for i := 0; i < f.Shards(); i++ {
+			if f.Shard(i).Len() == 0 {
+				t.Errorf("%s: shard %d is empty", name, i)
+			}
+		}
+
+		for _, w := range dictSm[:20] {
+			q := []byte(mess(w, 2))
+			r, want := f.Find(q, 2), bk.Find(q, 2)
+			slices.SortFunc(r, bytes.Compare)
+			slices.SortFunc(want, bytes.Compare)
+			if !slices.EqualFunc(r, want, bytes.Equal) {
+				t.Fatalf("%s: Find(%s) found %q, want %q", name, q, r, want)
+			}
+		}
+	}
+}
+
+func TestForestArguments(t *testing.T) {
+	for name, fn := range map[string]func(){
+		"no shards":  func() { NewForest(0, levenshteinFromBytes, HashPartition) },
+		"no pivots":  func() { PivotPartition(nil, levenshteinFromBytes) },
+		"zero width": func() { LengthPartition(0) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: did not panic", name)
+				}
+			}()
+			fn()
+		}()
+	}
+}
+
+func TestForestFiles(t *testing.T) {
+	dir := t.TempDir()
+	f := NewForest(3, levenshteinFromBytes, LengthPartition(4))
+	f.AddAll(wordsOf(dictSm))
+	if err := f.SaveToDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := NewForest(3, levenshteinFromBytes, LengthPartition(4))
+	if err := loaded.ReadFromDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Len() != len(dictSm) {
+		t.Fatalf("forest read from files holds %d words, want %d", loaded.Len(), len(dictSm))
+	}
+
+	// Shards are replaced one at a time.
+	f.Shard(0).Clear()
+	if err := f.SaveToDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(ShardPath(dir, 0)); !os.IsNotExist(err) {
+		t.Fatalf("file of an emptied shard left behind: %v", err)
+	}
+	if err := loaded.Shard(0).ReadFromFile(ShardPath(dir, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.ReadFromDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Shard(0).Len() != 0 || loaded.Len() != f.Len() {
+		t.Fatalf("forest holds %d words after reloading, want %d", loaded.Len(), f.Len())
+	}
+}
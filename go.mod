@@ -3,8 +3,10 @@ module github.com/theosiemensrhodes/go-bktree
 go 1.23.1
 
 require (
-	github.com/agnivade/levenshtein v1.2.0 // indirect
-	github.com/gogo/protobuf v1.3.2 // indirect
-	github.com/golang/protobuf v1.5.4 // indirect
-	google.golang.org/protobuf v1.33.0 // indirect
+	github.com/agnivade/levenshtein v1.2.0
+	github.com/gogo/protobuf v1.3.2
+	github.com/golang/protobuf v1.5.4
+	golang.org/x/text v0.28.0
 )
+
+require google.golang.org/protobuf v1.33.0 // indirect
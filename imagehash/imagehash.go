@@ -0,0 +1,288 @@
+// Package imagehash computes perceptual hashes of images for use as BK-tree keys.
+//
+// Perceptually similar images produce hashes with a small Hamming distance, so
+// a tree built with bktree.Hamming can be searched for near-duplicate images.
+package imagehash
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	_ "image/gif"  // register GIF decoder
+	_ "image/jpeg" // register JPEG decoder
+	_ "image/png"  // register PNG decoder
+	"io/fs"
+	"math"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"sort"
+
+	bktree "github.com/theosiemensrhodes/go-bktree"
+)
+
+// Hash is a 64-bit perceptual image hash.
+type Hash uint64
+
+// Bytes returns the big-endian encoding of h, suitable as a BK-tree key.
+func (h Hash) Bytes() []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(h))
+	return b
+}
+
+// Distance returns the Hamming distance between h and o.
+func (h Hash) Distance(o Hash) int {
+	return bits.OnesCount64(uint64(h ^ o))
+}
+
+// FromBytes decodes a key produced by Hash.Bytes.
+func FromBytes(b []byte) Hash {
+	return Hash(binary.BigEndian.Uint64(b))
+}
+
+// Func computes a hash from an image.
+type Func func(img image.Image) Hash
+
+// AHash computes the average hash of img: each bit tells whether a cell of an
+// 8x8 grayscale thumbnail is brighter than the thumbnail's mean.
+func AHash(img image.Image) Hash {
+	px := grayscale(img, 8, 8)
+	mean := 0.0
+	for _, v := range px {
+		mean += v
+	}
+	mean /= float64(len(px))
+
+	var h Hash
+	for i, v := range px {
+		if v > mean {
+			h |= 1 << uint(63-i)
+		}
+	}
+	return h
+}
+
+// DHash computes the difference hash of img: each bit tells whether a cell of a
+// 9x8 grayscale thumbnail is brighter than its right-hand neighbour.
+func DHash(img image.Image) Hash {
+	px := grayscale(img, 9, 8)
+
+	var h Hash
+	i := 0
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			if px[y*9+x] > px[y*9+x+1] {
+				h |= 1 << uint(63-i)
+			}
+			i++
+		}
+	}
+	return h
+}
+
+// PHash computes the DCT-based perceptual hash of img: the lowest 8x8
+// frequencies of a 32x32 grayscale thumbnail are compared against their median.
+func PHash(img image.Image) Hash {
+	const n = 32
+	px := grayscale(img, n, n)
+	coef := dct2(px, n)
+
+	low := make([]float64, 0, 64)
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			low = append(low, coef[y*n+x])
+		}
+	}
+
+	// The DC term is excluded from the median since it only reflects overall brightness.
+	sorted := append([]float64(nil), low[1:]...)
+	sort.Float64s(sorted)
+	median := sorted[len(sorted)/2]
+
+	var h Hash
+	for i, v := range low {
+		if v > median {
+			h |= 1 << uint(63-i)
+		}
+	}
+	return h
+}
+
+// HashFile decodes the image stored at path and hashes it with fn.
+func HashFile(path string, fn Func) (Hash, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return 0, err
+	}
+	return fn(img), nil
+}
+
+// Clusters walks dir, hashes every decodable image with fn and returns groups of
+// files whose hashes are linked by chains of Hamming distances of at most radius.
+// Files not in PNG, JPEG or GIF format are skipped, while files that cannot be
+// read, and corrupt images, stop the walk with an error. Only groups of two or
+// more files are reported; each group and the list of groups are sorted.
+func Clusters(dir string, radius int, fn Func) ([][]string, error) {
+	files := map[Hash][]string{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		h, err := HashFile(path, fn)
+		if errors.Is(err, image.ErrFormat) {
+			return nil // Not an image
+		} else if err != nil {
+			return err
+		}
+		files[h] = append(files[h], path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]Hash, 0, len(files))
+	for h := range files {
+		hashes = append(hashes, h)
+	}
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+
+	bk := bktree.New(bktree.Hamming)
+	for _, h := range hashes {
+		bk.Add(h.Bytes())
+	}
+
+	parent := make(map[Hash]Hash, len(hashes))
+	var root func(h Hash) Hash
+	root = func(h Hash) Hash {
+		p, ok := parent[h]
+		if !ok || p == h {
+			return h
+		}
+		r := root(p)
+		parent[h] = r
+		return r
+	}
+	for _, h := range hashes {
+		for _, k := range bk.Find(h.Bytes(), int64(radius)) {
+			a, b := root(h), root(FromBytes(k))
+			if a != b {
+				parent[b] = a
+			}
+		}
+	}
+
+	groups := map[Hash][]string{}
+	for _, h := range hashes {
+		r := root(h)
+		groups[r] = append(groups[r], files[h]...)
+	}
+
+	r := [][]string{}
+	for _, g := range groups {
+		if len(g) < 2 {
+			continue
+		}
+		sort.Strings(g)
+		r = append(r, g)
+	}
+	sort.Slice(r, func(i, j int) bool { return r[i][0] < r[j][0] })
+	return r, nil
+}
+
+// grayscale returns the luminance of img scaled to w x h cells. Dimensions
+// larger than the grid are averaged over the source pixels each cell covers;
+// smaller ones, as in thumbnails and icons, are interpolated linearly.
+func grayscale(img image.Image, w, h int) []float64 {
+	b := img.Bounds()
+	px := make([]float64, w*h)
+	if b.Empty() {
+		return px
+	}
+
+	rows := make([]float64, 0, b.Dy()*w) // Source rows scaled to w cells
+	lum := make([]float64, b.Dx())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			lum[x-b.Min.X] = 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(bl)
+		}
+		rows = append(rows, resample(lum, w)...)
+	}
+
+	col := make([]float64, b.Dy())
+	for cx := 0; cx < w; cx++ {
+		for y := range col {
+			col[y] = rows[y*w+cx]
+		}
+		for cy, v := range resample(col, h) {
+			px[cy*w+cx] = v
+		}
+	}
+	return px
+}
+
+// resample scales the values of src to m cells, averaging the values each
+// cell covers or, when src is shorter, interpolating between them.
+func resample(src []float64, m int) []float64 {
+	n := len(src)
+	dst := make([]float64, m)
+	for c := range dst {
+		if n >= m {
+			lo, hi := (c*n+m-1)/m, ((c+1)*n+m-1)/m
+			for _, v := range src[lo:hi] {
+				dst[c] += v
+			}
+			dst[c] /= float64(hi - lo)
+			continue
+		}
+		p := min(max((float64(c)+0.5)*float64(n)/float64(m)-0.5, 0), float64(n-1))
+		i := int(p)
+		j := min(i+1, n-1)
+		dst[c] = src[i] + (src[j]-src[i])*(p-float64(i))
+	}
+	return dst
+}
+
+// dct2 computes the two-dimensional DCT-II of the n x n matrix px.
+func dct2(px []float64, n int) []float64 {
+	cos := make([]float64, n*n)
+	for k := 0; k < n; k++ {
+		for i := 0; i < n; i++ {
+			cos[k*n+i] = math.Cos(math.Pi / float64(n) * (float64(i) + 0.5) * float64(k))
+		}
+	}
+
+	rows := make([]float64, n*n)
+	for y := 0; y < n; y++ {
+		for k := 0; k < n; k++ {
+			s := 0.0
+			for x := 0; x < n; x++ {
+				s += px[y*n+x] * cos[k*n+x]
+			}
+			rows[y*n+k] = s
+		}
+	}
+
+	out := make([]float64, n*n)
+	for x := 0; x < n; x++ {
+		for k := 0; k < n; k++ {
+			s := 0.0
+			for y := 0; y < n; y++ {
+				s += rows[y*n+x] * cos[k*n+y]
+			}
+			out[k*n+x] = s
+		}
+	}
+	return out
+}
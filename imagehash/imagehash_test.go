@@ -0,0 +1,132 @@
+package imagehash
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// gradient returns a w x h image whose brightness rises diagonally, optionally
+// with a bright square painted over the top-left corner.
+func gradient(w, h int, spot bool) image.Image {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := uint8((x*255/w + y*255/h) / 2)
+			if spot && x < w/3 && y < h/3 {
+				v = 255
+			}
+			img.SetGray(x, y, color.Gray{Y: v})
+		}
+	}
+	return img
+}
+
+// pattern returns a w x h image of smooth waves, the same at any size.
+func pattern(w, h int) image.Image {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			u, v := (float64(x)+0.5)/float64(w), (float64(y)+0.5)/float64(h)
+			l := 128 + 50*math.Sin(2.3*u+1) + 40*math.Cos(3.1*v) + 30*math.Sin(4*u*v+0.5)
+			img.SetGray(x, y, color.Gray{Y: uint8(l)})
+		}
+	}
+	return img
+}
+
+func TestHashes(t *testing.T) {
+	for name, fn := range map[string]Func{"ahash": AHash, "dhash": DHash, "phash": PHash} {
+		a := fn(gradient(64, 64, false))
+		b := fn(gradient(128, 128, false))
+		c := fn(gradient(64, 64, true))
+
+		if d := a.Distance(b); d > 4 {
+			t.Errorf("%s: rescaled image at distance %d", name, d)
+		}
+		if d := a.Distance(c); d <= a.Distance(b) {
+			t.Errorf("%s: modified image at distance %d, rescaled at %d", name, d, a.Distance(b))
+		}
+	}
+}
+
+func TestHashesSmall(t *testing.T) {
+	// Images with half as many pixels as the grid of the hash on either side.
+	grids := map[string]struct {
+		fn   Func
+		w, h int
+	}{"ahash": {AHash, 8, 8}, "dhash": {DHash, 9, 8}, "phash": {PHash, 32, 32}}
+	for name, g := range grids {
+		a := g.fn(pattern(64, 64))
+		if d := a.Distance(g.fn(pattern(g.w/2, g.h/2))); d > 8 {
+			t.Errorf("%s: %dx%d copy at distance %d", name, g.w/2, g.h/2, d)
+		}
+		if d := a.Distance(g.fn(pattern(g.w/2, 64))); d > 8 {
+			t.Errorf("%s: %dx64 copy at distance %d", name, g.w/2, d)
+		}
+	}
+}
+
+func TestPHashMedian(t *testing.T) {
+	// Of the 63 coefficients besides the DC term, 31 lie above their median.
+	for _, size := range []int{32, 64, 100} {
+		h := PHash(pattern(size, size))
+		if n := bits.OnesCount64(uint64(h) &^ (1 << 63)); n != 31 {
+			t.Errorf("%dx%d: %d coefficients above the median", size, size, n)
+		}
+	}
+}
+
+func TestBytes(t *testing.T) {
+	h := Hash(0x0123456789abcdef)
+	if FromBytes(h.Bytes()) != h {
+		t.Fatal("hash does not round-trip through Bytes")
+	}
+}
+
+func TestClusters(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, img image.Image) {
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if err := png.Encode(f, img); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a.png", gradient(64, 64, false))
+	write("b.png", gradient(96, 96, false))
+	write("c.png", gradient(64, 64, true))
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not an image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Clusters(dir, 4, DHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{filepath.Join(dir, "a.png"), filepath.Join(dir, "b.png")}}
+	if !reflect.DeepEqual(r, want) {
+		t.Fatalf("Clusters = %v, want %v", r, want)
+	}
+
+	// A truncated PNG is reported rather than skipped.
+	data, err := os.ReadFile(filepath.Join(dir, "a.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "d.png"), data[:len(data)/2], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Clusters(dir, 4, DHash); err == nil {
+		t.Fatal("Clusters skipped a truncated image")
+	}
+}
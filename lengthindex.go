@@ -0,0 +1,104 @@
+package bktree
+
+import (
+	"maps"
+	"slices"
+	"unicode/utf8"
+)
+
+// LowerBound returns a lower bound on the distance between two words whose
+// keys have lengths la and lb.
+type LowerBound func(la, lb int) int64
+
+// LengthDifference is the lower bound of metrics counting single character
+// insertions and deletions, such as the Levenshtein distance.
+func LengthDifference(la, lb int) int64 {
+	return int64(max(la-lb, lb-la))
+}
+
+// LengthIndex keeps one BK-tree per key length, so that searches skip the
+// lengths whose words cannot lie within range of the query. A LengthIndex
+// with Metric and Bound set is ready to use.
+type LengthIndex struct {
+	Metric Metric     // Metric function, required
+	Bound  LowerBound // Lower bound of Metric given key lengths, required
+
+	// Length measures keys; it defaults to utf8.RuneCount, matching metrics
+	// over characters. Metrics over bytes need len.
+	Length func(data []byte) int
+
+	// Normalizer, if set, is applied to words at Add and to queries at Find.
+	Normalizer Normalizer
+
+	buckets map[int]*BKTree
+}
+
+// NewLengthIndex returns an empty index over the metric m, bounded below by
+// bound.
+func NewLengthIndex(m Metric, bound LowerBound) *LengthIndex {
+	return &LengthIndex{Metric: m, Bound: bound}
+}
+
+func (x *LengthIndex) key(data []byte) ([]byte, int) {
+	if x.Normalizer != nil {
+		data = x.Normalizer(data)
+	}
+	if x.Length == nil {
+		return data, utf8.RuneCount(data)
+	}
+	return data, x.Length(data)
+}
+
+// Add inserts a new word into the tree of its key length.
+func (x *LengthIndex) Add(data []byte) {
+	key, l := x.key(data)
+	t, ok := x.buckets[l]
+	if !ok {
+		if x.buckets == nil {
+			x.buckets = map[int]*BKTree{}
+		}
+		t = New(x.Metric)
+		x.buckets[l] = t
+	}
+	t.add(key, data)
+}
+
+// AddAll inserts every word of data into the index.
+func (x *LengthIndex) AddAll(data [][]byte) {
+	for _, w := range data {
+		x.Add(w)
+	}
+}
+
+// Len returns the number of words in the index.
+func (x *LengthIndex) Len() int {
+	n := 0
+	for _, t := range x.buckets {
+		n += t.Len()
+	}
+	return n
+}
+
+// Lengths returns the key lengths holding words, in increasing order.
+func (x *LengthIndex) Lengths() []int {
+	return slices.Sorted(maps.Keys(x.buckets))
+}
+
+// Bucket returns the tree holding the words of key length l, or nil.
+func (x *LengthIndex) Bucket(l int) *BKTree {
+	return x.buckets[l]
+}
+
+// Find returns the words within distance n of data, searching only the trees
+// of key lengths whose lower bound is at most n. Results are grouped by key
+// length, in increasing order.
+func (x *LengthIndex) Find(data []byte, n int64) [][]byte {
+	key, l := x.key(data)
+	r := [][]byte{}
+	for _, k := range x.Lengths() {
+		if x.Bound(l, k) <= n {
+			r = append(r, x.buckets[k].Find(key, n)...)
+		}
+	}
+	return r
+}
@@ -0,0 +1,90 @@
+package bktree
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+)
+
+func TestLengthIndex(t *testing.T) {
+	bk := New(levenshteinFromBytes)
+	bk.AddAll(wordsOf(dictLg))
+
+	calls := 0
+	x := NewLengthIndex(func(a, b []byte) int {
+		calls++
+		return levenshteinFromBytes(a, b)
+	}, LengthDifference)
+	x.AddAll(wordsOf(dictLg))
+	if x.Len() != len(dictLg) {
+		t.Fatalf("index holds %d words, want %d", x.Len(), len(dictLg))
+	}
+	for _, l := range x.Lengths() {
+		x.Bucket(l).Walk(func(data []byte, _ int) bool {
+			if len(data) != l {
+				t.Fatalf("%s in bucket %d", data, l)
+			}
+			return true
+		})
+	}
+
+	for _, w := range dictSm[:20] {
+		q := []byte(mess(w, 2))
+		calls = 0
+		r, want := x.Find(q, 2), bk.Find(q, 2)
+		slices.SortFunc(r, bytes.Compare)
+		slices.SortFunc(want, bytes.Compare)
+		if !slices.EqualFunc(r, want, bytes.Equal) {
+			t.Fatalf("Find(%s) found %q, want %q", q, r, want)
+		}
+		if calls == 0 || calls > len(dictLg) {
+			t.Fatalf("Find(%s) made %d metric calls", q, calls)
+		}
+	}
+
+	folded := NewLengthIndex(levenshteinFromBytes, LengthDifference)
+	folded.Normalizer = FoldCase
+	folded.Add([]byte("Straße"))
+	if r := folded.Find([]byte("STRASSE"), 1); len(r) != 1 || string(r[0]) != "Straße" {
+		t.Fatalf("normalized Find found %q", r)
+	}
+
+	lit := &LengthIndex{Metric: levenshteinFromBytes, Bound: LengthDifference}
+	if lit.Len() != 0 || len(lit.Find([]byte("book"), 1)) != 0 || lit.Bucket(4) != nil {
+		t.Fatal("empty index literal holds words")
+	}
+	lit.Add([]byte("book"))
+	if r := lit.Find([]byte("boo"), 1); len(r) != 1 || lit.Len() != 1 {
+		t.Fatalf("index literal found %q", r)
+	}
+}
+
+func BenchmarkFindLengthIndexLg(b *testing.B) {
+	x := NewLengthIndex(levenshteinFromBytes, LengthDifference)
+	x.AddAll(wordsOf(dictLg))
+
+	s := []string{}
+	for i := 0; i < b.N; i++ {
+		s = append(s, pick(dictLg))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		x.Find([]byte(s[i]), 2)
+	}
+}
+
+func BenchmarkFindTreeLg(b *testing.B) {
+	bk := New(levenshteinFromBytes)
+	bk.AddAll(wordsOf(dictLg))
+
+	s := []string{}
+	for i := 0; i < b.N; i++ {
+		s = append(s, pick(dictLg))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bk.Find([]byte(s[i]), 2)
+	}
+}
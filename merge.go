@@ -0,0 +1,76 @@
+package bktree
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// ErrMetricMismatch is returned when merging a tree built with another metric
+// or normalizer.
+var ErrMetricMismatch = errors.New("bktree: tree built with a different metric")
+
+// mergeSample is the number of edges of a merged tree checked against the
+// metric of the receiving tree.
+const mergeSample = 64
+
+// Merge adds every word of other to the tree. Before adding anything, it
+// checks on a sample of the edges of other that both trees measure distances
+// alike, and that the tree normalizes words into the keys of other; if not,
+// it returns an error wrapping ErrMetricMismatch.
+func (t *BKTree) Merge(other *BKTree) error {
+	root := other.load().root
+	if root == nil {
+		return nil
+	}
+	nodes := []*node{}
+	root.walk(func(e *node, _ int) bool {
+		nodes = append(nodes, e)
+		return true
+	}, 0)
+	if err := t.checkMerge(nodes); err != nil {
+		return err
+	}
+	for _, e := range nodes {
+		t.add(e.Data, e.value())
+	}
+	return nil
+}
+
+// checkMerge evaluates the metric on evenly spread edges of nodes.
+func (t *BKTree) checkMerge(nodes []*node) error {
+	step := max(len(nodes)/mergeSample, 1)
+	for i := 0; i < len(nodes); i += step {
+		e := nodes[i]
+		if !bytes.Equal(t.normalize(e.value()), e.Data) {
+			return fmt.Errorf("%w: %q is indexed as %q, not %q", ErrMetricMismatch, e.value(), e.Data, t.normalize(e.value()))
+		}
+		if len(e.Children) == 0 || e.Children[len(e.Children)-1].Node == nil {
+			continue
+		}
+		// The farthest child, since the distance to duplicates rarely tells metrics apart
+		d, c := e.Children[len(e.Children)-1].Dist, e.Children[len(e.Children)-1].Node
+		if l := int64(t.Metric(e.Data, c.Data)); l != d {
+			return fmt.Errorf("%w: %q stored at distance %d from %q, which the metric puts at %d", ErrMetricMismatch, c.Data, d, e.Data, l)
+		}
+	}
+	return nil
+}
+
+// MergeFiles merges the trees saved in files into the tree, then saves it to
+// out. The files are read one at a time, so that only the receiving tree and
+// one source tree are held in memory. It stops at the first file that cannot
+// be read or merged, leaving the words already merged in the tree.
+func (t *BKTree) MergeFiles(out string, files ...string) error {
+	for _, f := range files {
+		src := New(t.Metric)
+		if err := src.ReadFromFile(f); err != nil {
+			return err
+		}
+		if err := t.Merge(src); err != nil {
+			return fmt.Errorf("%s: %w", f, err)
+		}
+	}
+	_, err := t.SaveToFile(out)
+	return err
+}
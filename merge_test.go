@@ -0,0 +1,83 @@
+package bktree
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	a, b := New(levenshteinFromBytes), New(levenshteinFromBytes)
+	a.AddAll(wordsOf(dictLg[:500]))
+	b.AddAll(wordsOf(dictLg[400:]))
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	if a.Len() != len(dictLg)+100 {
+		t.Fatalf("merged tree holds %d words, want %d", a.Len(), len(dictLg)+100)
+	}
+	if err := a.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if r, _ := a.Get([]byte(dictLg[450])); len(r) != 2 {
+		t.Fatalf("word in both trees found %d times", len(r))
+	}
+	if err := a.Merge(New(levenshteinFromBytes)); err != nil || a.Len() != len(dictLg)+100 {
+		t.Fatalf("merging an empty tree returned %v", err)
+	}
+
+	// Length differences do not measure the edges of a Levenshtein tree.
+	c := New(func(a, b []byte) int { return max(len(a)-len(b), len(b)-len(a)) })
+	if err := c.Merge(b); !errors.Is(err, ErrMetricMismatch) || c.Len() != 0 {
+		t.Fatalf("merging a tree with another metric returned %v and added %d words", err, c.Len())
+	}
+
+	folded := New(levenshteinFromBytes)
+	folded.Normalizer = FoldCase
+	upper := New(levenshteinFromBytes)
+	upper.Add([]byte("Book"))
+	if err := folded.Merge(upper); !errors.Is(err, ErrMetricMismatch) {
+		t.Fatalf("merging a tree with another normalizer returned %v", err)
+	}
+
+	folded.Add([]byte("Book"))
+	plain := New(levenshteinFromBytes)
+	if err := plain.Merge(folded); !errors.Is(err, ErrMetricMismatch) || plain.Len() != 0 {
+		t.Fatalf("merging a normalized tree into a plain one returned %v and added %d words", err, plain.Len())
+	}
+}
+
+func TestMergeFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{}
+	for i := 0; i < 3; i++ {
+		bk := New(levenshteinFromBytes)
+		bk.AddAll(wordsOf(dictLg[i*100 : (i+1)*100]))
+		files = append(files, filepath.Join(dir, fmt.Sprintf("day%d.db", i)))
+		if _, err := bk.SaveToFile(files[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out := filepath.Join(dir, "all.db")
+	if err := New(levenshteinFromBytes).MergeFiles(out, files...); err != nil {
+		t.Fatal(err)
+	}
+	merged := New(levenshteinFromBytes)
+	if err := merged.ReadFromFile(out); err != nil {
+		t.Fatal(err)
+	}
+	if merged.Len() != 300 {
+		t.Fatalf("merged file holds %d words, want 300", merged.Len())
+	}
+	for _, w := range dictLg[:300] {
+		if !merged.Contains([]byte(w)) {
+			t.Fatalf("merged file lacks %s", w)
+		}
+	}
+
+	if err := New(levenshteinFromBytes).MergeFiles(out, filepath.Join(dir, "missing.db")); err == nil {
+		t.Fatal("merging a missing file succeeded")
+	}
+}
@@ -0,0 +1,157 @@
+package bktree
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"math/rand/v2"
+)
+
+// Hamming is a Metric counting the number of differing bits between a and b.
+// Bytes past the end of the shorter slice count as fully differing, so keys of
+// unequal length remain comparable.
+func Hamming(a, b []byte) int {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	d := 8 * (len(b) - len(a))
+	for i := range a {
+		d += bits.OnesCount8(a[i] ^ b[i])
+	}
+	return d
+}
+
+// Axiom identifies one of the metric axioms a BK-tree relies on.
+type Axiom int
+
+const (
+	NonNegativity Axiom = iota // d(a, b) >= 0
+	Identity                   // d(a, a) == 0
+	Symmetry                   // d(a, b) == d(b, a)
+	Triangle                   // d(a, c) <= d(a, b) + d(b, c)
+)
+
+func (a Axiom) String() string {
+	switch a {
+	case NonNegativity:
+		return "non-negativity"
+	case Identity:
+		return "identity"
+	case Symmetry:
+		return "symmetry"
+	case Triangle:
+		return "triangle inequality"
+	}
+	return fmt.Sprintf("Axiom(%d)", int(a))
+}
+
+// Violation describes a set of values for which a Metric breaks an axiom.
+type Violation struct {
+	Axiom   Axiom
+	A, B, C []byte // C is only set for triangle inequality violations
+	Detail  string // Offending distances
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s violated: %s", v.Axiom, v.Detail)
+}
+
+// maxTriples is the number of triples of samples above which ValidateMetric
+// checks the triangle inequality on random triples only.
+const maxTriples = 1 << 20
+
+// ValidateMetric checks m against the metric axioms on the given samples and
+// returns every violation found. Pairwise axioms are checked on all pairs of
+// samples, so m is evaluated len(samples)^2 times; pass a representative
+// subset for large vocabularies. The triangle inequality is checked on every
+// triple of distinct samples up to about a hundred samples, and beyond that on
+// maxTriples triples drawn with a fixed seed, so that its cost stays bounded.
+func ValidateMetric(m Metric, samples [][]byte) []Violation {
+	k := len(samples)
+	d := make([]int, k*k)
+	for i, a := range samples {
+		for j, b := range samples {
+			d[i*k+j] = m(a, b)
+		}
+	}
+
+	r := []Violation{}
+	for i, a := range samples {
+		r = append(r, checkIdentity(a, d[i*k+i])...)
+		for j := i + 1; j < k; j++ {
+			r = append(r, checkPair(a, samples[j], d[i*k+j], d[j*k+i])...)
+		}
+	}
+	triangle := func(i, j, l int) {
+		if i == j || j == l || i == l {
+			return
+		}
+		if v, ok := checkTriangle(samples[i], samples[j], samples[l], d[i*k+j], d[j*k+l], d[i*k+l]); !ok {
+			r = append(r, v)
+		}
+	}
+	if k*(k-1)*(k-2) <= maxTriples {
+		for i := range samples {
+			for j := range samples {
+				for l := range samples {
+					triangle(i, j, l)
+				}
+			}
+		}
+	} else {
+		rng := rand.New(rand.NewPCG(1, 2))
+		for n := 0; n < maxTriples; n++ {
+			triangle(rng.IntN(k), rng.IntN(k), rng.IntN(k))
+		}
+	}
+	return r
+}
+
+func checkIdentity(a []byte, aa int) []Violation {
+	r := []Violation{}
+	if aa < 0 {
+		r = append(r, Violation{Axiom: NonNegativity, A: a, B: a, Detail: fmt.Sprintf("d(a, a) = %d", aa)})
+	}
+	if aa != 0 {
+		r = append(r, Violation{Axiom: Identity, A: a, B: a, Detail: fmt.Sprintf("d(a, a) = %d", aa)})
+	}
+	return r
+}
+
+func checkPair(a, b []byte, ab, ba int) []Violation {
+	r := []Violation{}
+	if ab < 0 || ba < 0 {
+		r = append(r, Violation{Axiom: NonNegativity, A: a, B: b, Detail: fmt.Sprintf("d(a, b) = %d, d(b, a) = %d", ab, ba)})
+	}
+	if ab != ba {
+		r = append(r, Violation{Axiom: Symmetry, A: a, B: b, Detail: fmt.Sprintf("d(a, b) = %d, d(b, a) = %d", ab, ba)})
+	}
+	return r
+}
+
+func checkTriangle(a, b, c []byte, ab, bc, ac int) (Violation, bool) {
+	if ac > ab+bc {
+		return Violation{Axiom: Triangle, A: a, B: b, C: c, Detail: fmt.Sprintf("d(a, c) = %d > d(a, b) + d(b, c) = %d + %d", ac, ab, bc)}, false
+	}
+	return Violation{}, true
+}
+
+// The FloatMetric type is a function measuring a real-valued distance between two given strings.
+type FloatMetric func(a, b []byte) float64
+
+// Quantize returns a Metric that places the distances measured by m into
+// integer buckets of the given width, bucket k holding [k*resolution, (k+1)*resolution).
+//
+// Quantized distances only satisfy the triangle inequality up to one bucket,
+// so trees over real-valued metrics should be built with NewFloat, which
+// accounts for the quantization error when searching.
+//
+// Quantize panics if resolution is not positive.
+func Quantize(m FloatMetric, resolution float64) Metric {
+	if !(resolution > 0) {
+		panic("bktree: resolution must be positive")
+	}
+	return func(a, b []byte) int {
+		return int(math.Floor(m(a, b) / resolution))
+	}
+}
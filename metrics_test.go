@@ -0,0 +1,154 @@
+package bktree
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestHamming(t *testing.T) {
+	cases := []struct {
+		a, b []byte
+		d    int
+	}{
+		{nil, nil, 0},
+		{[]byte{0xff}, []byte{0xff}, 0},
+		{[]byte{0x0f}, []byte{0xf0}, 8},
+		{[]byte{0x01, 0x02}, []byte{0x03, 0x02}, 1},
+		{[]byte{0x01}, []byte{0x01, 0x00}, 8},
+	}
+	for _, c := range cases {
+		if d := Hamming(c.a, c.b); d != c.d {
+			t.Errorf("Hamming(%x, %x) = %d, want %d", c.a, c.b, d, c.d)
+		}
+		if d := Hamming(c.b, c.a); d != c.d {
+			t.Errorf("Hamming(%x, %x) = %d, want %d", c.b, c.a, d, c.d)
+		}
+	}
+}
+
+func TestFindHamming(t *testing.T) {
+	bk := New(Hamming)
+	keys := [][]byte{{0x00}, {0x01}, {0x03}, {0x07}, {0xff}}
+	for _, k := range keys {
+		bk.Add(k)
+	}
+
+	r := bk.Find([]byte{0x00}, 2)
+	if len(r) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(r))
+	}
+}
+
+func TestValidateMetric(t *testing.T) {
+	samples := [][]byte{[]byte("cafe"), []byte("cake"), []byte("coke"), []byte("cook")}
+	if v := ValidateMetric(levenshteinFromBytes, samples); len(v) != 0 {
+		t.Fatalf("unexpected violations for Levenshtein: %v", v)
+	}
+
+	// Squared edit distance breaks the triangle inequality.
+	squared := func(a, b []byte) int {
+		d := levenshteinFromBytes(a, b)
+		return d * d
+	}
+	if !hasAxiom(ValidateMetric(squared, samples), Triangle) {
+		t.Fatal("expected triangle inequality violation")
+	}
+
+	asymmetric := func(a, b []byte) int {
+		return max(len(a)-len(b), 0)
+	}
+	v := ValidateMetric(asymmetric, [][]byte{[]byte("a"), []byte("abc")})
+	if !hasAxiom(v, Symmetry) {
+		t.Fatal("expected symmetry violation")
+	}
+
+	shifted := func(a, b []byte) int {
+		return levenshteinFromBytes(a, b) - 1
+	}
+	v = ValidateMetric(shifted, samples[:2])
+	if !hasAxiom(v, Identity) || !hasAxiom(v, NonNegativity) {
+		t.Fatalf("expected identity and non-negativity violations, got %v", v)
+	}
+
+	// Beyond about a hundred samples, triangles are sampled.
+	many := wordsOf(dictLg[:300])
+	if v := ValidateMetric(levenshteinFromBytes, many); len(v) != 0 {
+		t.Fatalf("unexpected violations for Levenshtein: %v", v[0])
+	}
+	if v := ValidateMetric(squared, many); !hasAxiom(v, Triangle) || len(v) > maxTriples {
+		t.Fatalf("found %d violations among sampled triangles", len(v))
+	}
+}
+
+func TestOnViolation(t *testing.T) {
+	squared := func(a, b []byte) int {
+		d := levenshteinFromBytes(a, b)
+		return d * d
+	}
+
+	var v []Violation
+	bk := New(squared)
+	bk.OnViolation = func(x Violation) { v = append(v, x) }
+	for _, w := range dictSm {
+		bk.Add([]byte(w))
+	}
+	if !hasAxiom(v, Triangle) {
+		t.Fatal("expected triangle inequality violation during Add")
+	}
+
+	v = nil
+	bk = New(levenshteinFromBytes)
+	bk.OnViolation = func(x Violation) { v = append(v, x) }
+	for _, w := range dictSm {
+		bk.Add([]byte(w))
+	}
+	if len(v) != 0 {
+		t.Fatalf("unexpected violations for Levenshtein: %v", v)
+	}
+}
+
+func hasAxiom(v []Violation, a Axiom) bool {
+	for _, x := range v {
+		if x.Axiom == a {
+			return true
+		}
+	}
+	return false
+}
+
+func TestQuantize(t *testing.T) {
+	q := Quantize(absDiff, 0.5)
+	cases := []struct {
+		a, b string
+		d    int
+	}{
+		{"1", "1", 0},
+		{"1", "1.49", 0},
+		{"1", "1.5", 1},
+		{"0", "2.2", 4},
+	}
+	for _, c := range cases {
+		if d := q([]byte(c.a), []byte(c.b)); d != c.d {
+			t.Errorf("Quantize(%s, %s) = %d, want %d", c.a, c.b, d, c.d)
+		}
+	}
+
+	for _, res := range []float64{0, -1, math.NaN()} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Quantize with resolution %v did not panic", res)
+				}
+			}()
+			Quantize(absDiff, res)
+		}()
+	}
+}
+
+// absDiff is a FloatMetric over decimal numbers.
+func absDiff(a, b []byte) float64 {
+	x, _ := strconv.ParseFloat(string(a), 64)
+	y, _ := strconv.ParseFloat(string(b), 64)
+	return math.Abs(x - y)
+}
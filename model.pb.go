@@ -30,6 +30,7 @@ const _ = proto.ProtoPackageIsVersion1
 type Node struct {
 	Data     []byte          `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
 	Children map[int64]*Node `protobuf:"bytes,2,rep,name=children" json:"children,omitempty" protobuf_key:"varint,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Value    []byte          `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
 }
 
 func (m *Node) Reset()                    { *m = Node{} }
@@ -49,15 +50,15 @@ func init() {
 }
 
 var fileDescriptor0 = []byte{
-	// 145 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xe2, 0xe2, 0xce, 0xcd, 0x4f, 0x49,
-	0xcd, 0xd1, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x57, 0xea, 0x67, 0xe4, 0x62, 0xf1, 0x03, 0xf2, 0x85,
-	0x84, 0xb8, 0x58, 0x52, 0x12, 0x4b, 0x12, 0x25, 0x18, 0x15, 0x18, 0x35, 0x78, 0x82, 0xc0, 0x6c,
-	0x21, 0x7d, 0x2e, 0x8e, 0xe4, 0x8c, 0xcc, 0x9c, 0x94, 0xa2, 0xd4, 0x3c, 0x09, 0x26, 0x05, 0x66,
-	0x0d, 0x6e, 0x23, 0x61, 0x3d, 0x90, 0x62, 0x3d, 0x67, 0xa8, 0xa8, 0x6b, 0x5e, 0x49, 0x51, 0x65,
-	0x10, 0x5c, 0x91, 0x94, 0x13, 0x17, 0x2f, 0x8a, 0x94, 0x90, 0x00, 0x17, 0x73, 0x76, 0x6a, 0x25,
-	0xd8, 0x50, 0xe6, 0x20, 0x10, 0x53, 0x48, 0x9a, 0x8b, 0xb5, 0x2c, 0x31, 0xa7, 0x34, 0x15, 0x68,
-	0x20, 0x23, 0xd0, 0x40, 0x56, 0xb0, 0x81, 0x41, 0x10, 0x31, 0x2b, 0x26, 0x0b, 0xc6, 0x24, 0x36,
-	0xb0, 0xc3, 0x8c, 0x01, 0x01, 0x00, 0x00, 0xff, 0xff, 0x58, 0x45, 0x92, 0x02, 0xa7, 0x00, 0x00,
-	0x00,
+	// 153 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0xce, 0xcd, 0x4f, 0x49,
+	0xcd, 0xd1, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x57, 0x5a, 0xca, 0xc8, 0xc5, 0xe2, 0x97, 0x9f, 0x92,
+	0x2a, 0x24, 0xc4, 0xc5, 0x92, 0x92, 0x58, 0x92, 0x28, 0xc1, 0xa8, 0xc0, 0xa8, 0xc1, 0x13, 0x04,
+	0x66, 0x0b, 0xe9, 0x73, 0x71, 0x24, 0x67, 0x64, 0xe6, 0xa4, 0x14, 0xa5, 0xe6, 0x49, 0x30, 0x29,
+	0x30, 0x6b, 0x70, 0x1b, 0x09, 0xeb, 0x81, 0x14, 0xeb, 0x39, 0x43, 0x45, 0x5d, 0xf3, 0x4a, 0x8a,
+	0x2a, 0x83, 0xe0, 0x8a, 0x84, 0x44, 0xb8, 0x58, 0xcb, 0x12, 0x73, 0x4a, 0x53, 0x25, 0x98, 0xc1,
+	0xa6, 0x40, 0x38, 0x52, 0x4e, 0x5c, 0xbc, 0x28, 0x1a, 0x84, 0x04, 0xb8, 0x98, 0xb3, 0x53, 0x2b,
+	0xc1, 0x56, 0x31, 0x07, 0x81, 0x98, 0x42, 0xd2, 0x30, 0x8d, 0x4c, 0x0a, 0x8c, 0x1a, 0xdc, 0x46,
+	0xac, 0x60, 0x6b, 0xa0, 0xfa, 0xad, 0x98, 0x2c, 0x18, 0x93, 0xd8, 0xc0, 0xce, 0x35, 0x06, 0x0c,
+	0x00, 0xb9, 0x21, 0x0d, 0x55, 0xbd, 0x00, 0x00, 0x00,
 }
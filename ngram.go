@@ -0,0 +1,91 @@
+package bktree
+
+import (
+	"bytes"
+	"slices"
+)
+
+// NGramSet returns a Normalizer encoding a string as the sorted set of its
+// distinct n-grams of runes, to be measured by SymmetricDifference or
+// JaccardDistance. Strings shorter than n have a single n-gram, themselves.
+// NGramSet panics if n is less than one.
+func NGramSet(n int) Normalizer {
+	if n < 1 {
+		panic("bktree: n-grams must be at least one rune long")
+	}
+	return func(data []byte) []byte {
+		idx := []int{}
+		for i := range string(data) {
+			idx = append(idx, i)
+		}
+		idx = append(idx, len(data))
+
+		grams := [][]byte{}
+		if len(idx)-1 < n {
+			if len(data) > 0 {
+				grams = append(grams, data)
+			}
+		} else {
+			for i := 0; i+n < len(idx); i++ {
+				grams = append(grams, data[idx[i]:idx[i+n]])
+			}
+		}
+		slices.SortFunc(grams, bytes.Compare)
+		grams = slices.CompactFunc(grams, bytes.Equal)
+		return encodeTokens(grams)
+	}
+}
+
+// NewNGramTree returns an initialized BK-tree measuring strings by the number
+// of n-grams found in only one of them. Keys are stored as precomputed n-gram
+// sets, and a Normalizer set on the tree is applied before splitting n-grams.
+func NewNGramTree(n int) *BKTree {
+	t := New(SymmetricDifference)
+	t.encode = NGramSet(n)
+	return t
+}
+
+// NewJaccardTree returns an initialized BK-tree measuring strings by the
+// Jaccard distance between their n-gram sets, quantized into buckets of width
+// resolution. Keys are stored as precomputed n-gram sets, as for NewNGramTree,
+// and FindFloat searches with a real-valued radius between 0 and 1.
+func NewJaccardTree(n int, resolution float64) *BKTree {
+	t := NewFloat(JaccardDistance, resolution)
+	t.encode = NGramSet(n)
+	return t
+}
+
+// SymmetricDifference is a Metric counting the elements found in only one of
+// two sets encoded by NGramSet.
+func SymmetricDifference(a, b []byte) int {
+	common, na, nb := intersect(decodeTokens(a), decodeTokens(b))
+	return na + nb - 2*common
+}
+
+// JaccardDistance is a FloatMetric measuring one minus the ratio between the
+// intersection and the union of two sets encoded by NGramSet.
+func JaccardDistance(a, b []byte) float64 {
+	common, na, nb := intersect(decodeTokens(a), decodeTokens(b))
+	union := na + nb - common
+	if union == 0 {
+		return 0
+	}
+	return 1 - float64(common)/float64(union)
+}
+
+// intersect returns the size of the intersection of the sorted sets a and b, and their sizes.
+func intersect(a, b [][]byte) (common, na, nb int) {
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch c := bytes.Compare(a[i], b[j]); {
+		case c < 0:
+			i++
+		case c > 0:
+			j++
+		default:
+			common++
+			i++
+			j++
+		}
+	}
+	return common, len(a), len(b)
+}
@@ -0,0 +1,99 @@
+package bktree
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNGramSet(t *testing.T) {
+	toks := decodeTokens(NGramSet(2)([]byte("banana")))
+	want := []string{"an", "ba", "na"}
+	if len(toks) != len(want) {
+		t.Fatalf("n-grams of banana are %q, want %q", toks, want)
+	}
+	for i := range toks {
+		if string(toks[i]) != want[i] {
+			t.Fatalf("n-grams of banana are %q, want %q", toks, want)
+		}
+	}
+
+	if toks := decodeTokens(NGramSet(3)([]byte("éa"))); len(toks) != 1 || string(toks[0]) != "éa" {
+		t.Fatalf("n-grams of a short string are %q", toks)
+	}
+	if toks := decodeTokens(NGramSet(2)([]byte("né"))); len(toks) != 1 || string(toks[0]) != "né" {
+		t.Fatalf("n-grams are not split on runes: %q", toks)
+	}
+
+	for _, n := range []int{0, -1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NGramSet(%d) did not panic", n)
+				}
+			}()
+			NGramSet(n)
+		}()
+	}
+}
+
+func TestSetMetrics(t *testing.T) {
+	enc := NGramSet(2)
+	a, b := enc([]byte("night")), enc([]byte("nacht"))
+	// {ni, ig, gh, ht} and {na, ac, ch, ht} share one bigram
+	if d := SymmetricDifference(a, b); d != 6 {
+		t.Errorf("SymmetricDifference = %d, want 6", d)
+	}
+	if d := JaccardDistance(a, b); math.Abs(d-6.0/7) > 1e-9 {
+		t.Errorf("JaccardDistance = %f, want %f", d, 6.0/7)
+	}
+	if d := JaccardDistance(enc(nil), enc(nil)); d != 0 {
+		t.Errorf("JaccardDistance of empty sets = %f, want 0", d)
+	}
+
+	samples := [][]byte{}
+	for _, w := range dictSm[:20] {
+		samples = append(samples, enc([]byte(w)))
+	}
+	if v := ValidateMetric(SymmetricDifference, samples); len(v) != 0 {
+		t.Fatalf("unexpected violations: %v", v)
+	}
+}
+
+func TestSetTrees(t *testing.T) {
+	addresses := []string{
+		"221b baker street london",
+		"221 baker street london",
+		"10 downing street london",
+		"1600 pennsylvania avenue washington",
+	}
+	query := []byte("221b baker st london")
+
+	bk := NewJaccardTree(3, 0.05)
+	for _, w := range addresses {
+		bk.Add([]byte(w))
+	}
+	enc := NGramSet(3)
+	want := 0
+	for _, w := range addresses {
+		if JaccardDistance(enc(query), enc([]byte(w))) <= 0.5 {
+			want++
+		}
+	}
+	if r := bk.FindFloat(query, 0.5); len(r) != want || want != 2 {
+		t.Fatalf("Jaccard tree found %q, want %d matches", r, want)
+	}
+
+	bk = NewNGramTree(3)
+	for _, w := range addresses {
+		bk.Add([]byte(w))
+	}
+	if r := bk.Find([]byte("10 downing street london"), 0); len(r) != 1 || string(r[0]) != addresses[2] {
+		t.Fatalf("n-gram tree found %q", r)
+	}
+
+	bk.Normalizer = FoldCase
+	bk.Add([]byte("Elm Street Springfield"))
+	if r := bk.Find([]byte("ELM STREET SPRINGFIELD"), 0); len(r) != 1 {
+		t.Fatalf("n-gram tree with a Normalizer found %q", r)
+	}
+}
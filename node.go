@@ -0,0 +1,130 @@
+package bktree
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+)
+
+// node is the in-memory form of a Node. Its children are kept in a slice
+// sorted by distance, which takes far less memory than a map, leaves needing
+// none, and lets searches find the children in range by binary search.
+type node struct {
+	Data     []byte
+	Value    []byte // Only set when it differs from Data
+	Children []edge
+}
+
+// edge links a node to a child at the given distance.
+type edge struct {
+	Dist int64
+	Node *node
+}
+
+// fromProto converts a Node read from a file to its in-memory form.
+func fromProto(p *Node) *node {
+	e := &node{Data: p.Data, Value: p.Value}
+	if len(p.Children) > 0 {
+		e.Children = make([]edge, 0, len(p.Children))
+		for _, d := range slices.Sorted(maps.Keys(p.Children)) {
+			var c *node
+			if p.Children[d] != nil {
+				c = fromProto(p.Children[d])
+			}
+			e.Children = append(e.Children, edge{d, c})
+		}
+	}
+	return e
+}
+
+// toProto converts e to the Node message saved to files.
+func (e *node) toProto() *Node {
+	p := &Node{Data: e.Data, Value: e.Value, Children: make(map[int64]*Node, len(e.Children))}
+	for _, c := range e.Children {
+		if c.Node == nil {
+			p.Children[c.Dist] = nil
+		} else {
+			p.Children[c.Dist] = c.Node.toProto()
+		}
+	}
+	return p
+}
+
+// value returns the bytes originally added for e.
+func (e *node) value() []byte {
+	if e.Value != nil {
+		return e.Value
+	}
+	return e.Data
+}
+
+// locate returns the index of the child at distance d, or where it would be
+// inserted, and whether it exists.
+func (e *node) locate(d int64) (int, bool) {
+	return slices.BinarySearchFunc(e.Children, d, func(c edge, d int64) int {
+		return cmp.Compare(c.Dist, d)
+	})
+}
+
+// child returns the child at distance d, or nil.
+func (e *node) child(d int64) *node {
+	if i, ok := e.locate(d); ok {
+		return e.Children[i].Node
+	}
+	return nil
+}
+
+func (e *node) insert(n *node, m Metric) {
+	d := int64(m(e.Data, n.Data))
+	if i, ok := e.locate(d); !ok {
+		e.Children = slices.Insert(e.Children, i, edge{d, n})
+	} else if e.Children[i].Node == nil {
+		e.Children[i].Node = n // Nil children only come from corrupt files
+	} else {
+		e.Children[i].Node.insert(n, m)
+	}
+}
+
+// near calls fn for the children of e at distances between lo and hi, by
+// increasing distance from center, the lower of two distances equally far
+// from center first, skipping nil children. It stops and returns false as soon
+// as fn does.
+func (e *node) near(center, lo, hi int64, fn func(c *node) bool) bool {
+	// Children left of center are taken from i down, the others from j up.
+	i, _ := e.locate(min(center, hi+1))
+	i--
+	j, _ := e.locate(max(center, lo))
+	for {
+		left := i >= 0 && e.Children[i].Dist >= lo
+		right := j < len(e.Children) && e.Children[j].Dist <= hi
+		var c *node
+		switch {
+		case left && (!right || center-e.Children[i].Dist <= e.Children[j].Dist-center):
+			c = e.Children[i].Node
+			i--
+		case right:
+			c = e.Children[j].Node
+			j++
+		default:
+			return true
+		}
+		if c != nil && !fn(c) {
+			return false
+		}
+	}
+}
+
+// walk calls fn for e and every node under it, children in increasing order
+// of distance, skipping nil children, and reports whether fn returned true
+// every time.
+func (e *node) walk(fn func(e *node, depth int) bool, depth int) bool {
+	if !fn(e, depth) {
+		return false
+	}
+	for _, c := range e.Children {
+		if c.Node != nil && !c.Node.walk(fn, depth+1) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,115 @@
+package bktree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+func TestNodeProto(t *testing.T) {
+	bk := New(levenshteinFromBytes)
+	bk.AddAll(wordsOf(dictLg))
+	root := bk.load().root
+
+	p := root.toProto()
+	if !proto.Equal(fromProto(p).toProto(), p) {
+		t.Fatal("node does not round-trip through its protobuf form")
+	}
+
+	// The slice of children is searched in the order of the map.
+	for _, w := range dictSm[:20] {
+		q := []byte(mess(w, 2))
+		r, want := bk.Find(q, 2), p.Find(q, 2, levenshteinFromBytes, [][]byte{})
+		if len(r) != len(want) {
+			t.Fatalf("Find(%s) found %d words, want %d", q, len(r), len(want))
+		}
+		for i := range r {
+			if !bytes.Equal(r[i], want[i]) {
+				t.Fatalf("Find(%s) found %q at %d, want %q", q, r[i], i, want[i])
+			}
+		}
+	}
+}
+
+// hashes returns n random 64-bit keys, for a metric with a wide fan-out.
+func hashes(n int) [][]byte {
+	rng := rand.New(rand.NewPCG(1, 2))
+	r := make([][]byte, n)
+	for i := range r {
+		r[i] = binary.BigEndian.AppendUint64(nil, rng.Uint64())
+	}
+	return r
+}
+
+func benchmarkBuildSlice(b *testing.B, m Metric, words [][]byte, exact bool) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		bk := New(m)
+		bk.ExactIndex = exact
+		bk.AddAll(words)
+	}
+}
+
+func benchmarkBuildMap(b *testing.B, m Metric, words [][]byte) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		root := &Node{Data: words[0], Children: make(map[int64]*Node)}
+		for _, w := range words[1:] {
+			root.Add(w, m)
+		}
+	}
+}
+
+func benchmarkFindSlice(b *testing.B, m Metric, words [][]byte, n int64) {
+	bk := New(m)
+	bk.AddAll(words)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bk.Find(words[i%len(words)], n)
+	}
+}
+
+func benchmarkFindMap(b *testing.B, m Metric, words [][]byte, n int64) {
+	root := &Node{Data: words[0], Children: make(map[int64]*Node)}
+	for _, w := range words[1:] {
+		root.Add(w, m)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		root.Find(words[i%len(words)], n, m, [][]byte{})
+	}
+}
+
+func BenchmarkBuildSliceLg(b *testing.B) {
+	benchmarkBuildSlice(b, levenshteinFromBytes, wordsOf(dictLg), false)
+}
+
+func BenchmarkBuildSliceIndexLg(b *testing.B) {
+	benchmarkBuildSlice(b, levenshteinFromBytes, wordsOf(dictLg), true)
+}
+
+func BenchmarkBuildMapLg(b *testing.B) {
+	benchmarkBuildMap(b, levenshteinFromBytes, wordsOf(dictLg))
+}
+
+func BenchmarkFindSliceLg(b *testing.B) {
+	benchmarkFindSlice(b, levenshteinFromBytes, wordsOf(dictLg), 2)
+}
+
+func BenchmarkFindMapLg(b *testing.B) {
+	benchmarkFindMap(b, levenshteinFromBytes, wordsOf(dictLg), 2)
+}
+
+func BenchmarkBuildSliceHamming(b *testing.B) { benchmarkBuildSlice(b, Hamming, hashes(10000), false) }
+func BenchmarkBuildMapHamming(b *testing.B)   { benchmarkBuildMap(b, Hamming, hashes(10000)) }
+func BenchmarkFindSliceHamming(b *testing.B)  { benchmarkFindSlice(b, Hamming, hashes(10000), 12) }
+func BenchmarkFindMapHamming(b *testing.B)    { benchmarkFindMap(b, Hamming, hashes(10000), 12) }
+
+func BenchmarkBuildSliceIndexHamming(b *testing.B) {
+	benchmarkBuildSlice(b, Hamming, hashes(10000), true)
+}
@@ -0,0 +1,45 @@
+package bktree
+
+import (
+	"bytes"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// The Normalizer type is a function mapping a string to the canonical form under which it is indexed and searched.
+type Normalizer func(data []byte) []byte
+
+// Chain returns a Normalizer applying each of the given normalizers in order.
+func Chain(ns ...Normalizer) Normalizer {
+	return func(data []byte) []byte {
+		for _, n := range ns {
+			data = n(data)
+		}
+		return data
+	}
+}
+
+// FoldCase applies full Unicode case folding, so that "Straße" and "STRASSE" have the same form.
+func FoldCase(data []byte) []byte {
+	return cases.Fold().Bytes(data)
+}
+
+// CollapseSpace trims leading and trailing white space and replaces every
+// inner run of white space with a single ASCII space.
+func CollapseSpace(data []byte) []byte {
+	return bytes.Join(bytes.Fields(data), []byte(" "))
+}
+
+// StripDiacritics removes combining marks after canonical decomposition, so that "Café" becomes "Cafe".
+func StripDiacritics(data []byte) []byte {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	r, _, err := transform.Bytes(t, data)
+	if err != nil {
+		return data
+	}
+	return r
+}
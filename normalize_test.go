@@ -0,0 +1,67 @@
+package bktree
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestNormalizers(t *testing.T) {
+	cases := []struct {
+		n        Normalizer
+		in, want string
+	}{
+		{FoldCase, "CAFE", "cafe"},
+		{FoldCase, "Straße", "strasse"},
+		{CollapseSpace, "  new \t york\n", "new york"},
+		{StripDiacritics, "Café crème", "Cafe creme"},
+		{StripDiacritics, "Ångström", "Angstrom"},
+		{Chain(StripDiacritics, FoldCase, CollapseSpace), " Crème  BRÛLÉE ", "creme brulee"},
+	}
+	for _, c := range cases {
+		if got := string(c.n([]byte(c.in))); got != c.want {
+			t.Errorf("normalized %q to %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestFindNormalized(t *testing.T) {
+	bk := New(levenshteinFromBytes)
+	bk.Normalizer = Chain(StripDiacritics, FoldCase)
+	for _, w := range []string{"Café", "cafe", "CAFE", "cake", "coffee"} {
+		bk.Add([]byte(w))
+	}
+
+	check := func(bk *BKTree) {
+		t.Helper()
+		r := []string{}
+		for _, w := range bk.Find([]byte("cafÉ"), 0) {
+			r = append(r, string(w))
+		}
+		sort.Strings(r)
+		want := []string{"CAFE", "Café", "cafe"}
+		if len(r) != len(want) {
+			t.Fatalf("found %q, want %q", r, want)
+		}
+		for i := range r {
+			if r[i] != want[i] {
+				t.Fatalf("found %q, want %q", r, want)
+			}
+		}
+	}
+	check(bk)
+
+	filePath := filepath.Join(t.TempDir(), "normalized.db")
+	if _, err := bk.SaveToFile(filePath); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(filePath)
+
+	loaded := New(levenshteinFromBytes)
+	loaded.Normalizer = bk.Normalizer
+	if err := loaded.ReadFromFile(filePath); err != nil {
+		t.Fatal(err)
+	}
+	check(loaded)
+}
@@ -0,0 +1,113 @@
+package bktree
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ParallelOptions controls how FindParallel spreads a search over goroutines.
+type ParallelOptions struct {
+	Workers int // Maximum number of goroutines searching at once, GOMAXPROCS if zero
+	Depth   int // Number of levels whose subtrees are searched concurrently, 4 if zero
+}
+
+// FindParallel returns the same words as Find, in the same order, but searches
+// the subtrees selected at the upper levels of the tree concurrently. Subtrees
+// deeper than opts.Depth are searched sequentially, since they are too small
+// to be worth a goroutine.
+//
+// Trees created with NewFloat are searched sequentially. The tree must not be
+// modified while FindParallel runs. The Observer receives the totals over all
+// goroutines.
+func (t *BKTree) FindParallel(data []byte, n int64, opts ParallelOptions) [][]byte {
+	root := t.load().root
+	if root == nil || t.float != nil {
+		return t.Find(data, n)
+	}
+	start := time.Now()
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	depth := opts.Depth
+	if depth <= 0 {
+		depth = 4
+	}
+	// The calling goroutine is one of the workers.
+	p := &parallelQuery{data: t.normalize(data), n: n, metric: t.Metric, sem: make(chan struct{}, workers-1)}
+	r := root.findParallel(p, depth)
+	if t.Observer != nil {
+		t.Observer(QueryStats{
+			Visited:     int(p.visited.Load()),
+			MetricCalls: int(p.visited.Load()),
+			Pruned:      int(p.pruned.Load()),
+			Results:     len(r),
+			Elapsed:     time.Since(start),
+		})
+	}
+	return r
+}
+
+// parallelQuery is shared by the goroutines of a FindParallel search.
+type parallelQuery struct {
+	data   []byte
+	n      int64
+	metric Metric
+	sem    chan struct{}
+
+	visited, pruned atomic.Int64
+}
+
+func (e *node) findParallel(p *parallelQuery, depth int) [][]byte {
+	r := [][]byte{}
+	if depth == 0 {
+		q := &query{data: p.data, max: p.n, metric: p.metric, fn: func(x Match) bool {
+			r = append(r, x.Data)
+			return true
+		}}
+		e.search(q)
+		p.visited.Add(int64(q.visited))
+		p.pruned.Add(int64(q.pruned))
+		return r
+	}
+
+	n := p.n
+	l := int64(p.metric(e.Data, p.data))
+	p.visited.Add(1)
+	if l <= n {
+		r = append(r, e.value())
+	}
+
+	// Children are taken in the order Find visits them, so that
+	// concatenating their results reproduces its output.
+	children := []*node{}
+	e.near(l, max(l-n, 0), l+n, func(c *node) bool {
+		children = append(children, c)
+		return true
+	})
+	p.pruned.Add(int64(len(e.Children) - len(children)))
+
+	parts := make([][][]byte, len(children))
+	var wg sync.WaitGroup
+	for j, c := range children {
+		select {
+		case p.sem <- struct{}{}:
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-p.sem }()
+				parts[j] = c.findParallel(p, depth-1)
+			}()
+		default:
+			parts[j] = c.findParallel(p, depth-1)
+		}
+	}
+	wg.Wait()
+
+	for _, p := range parts {
+		r = append(r, p...)
+	}
+	return r
+}
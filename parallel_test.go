@@ -0,0 +1,46 @@
+package bktree
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFindParallel(t *testing.T) {
+	bk := New(levenshteinFromBytes)
+	bk.AddAll(wordsOf(dictLg))
+
+	for _, opts := range []ParallelOptions{{}, {Workers: 1}, {Workers: 3, Depth: 1}, {Workers: 16, Depth: 10}} {
+		for _, w := range dictSm[:20] {
+			q := []byte(mess(w, 2))
+			want := bk.Find(q, 3)
+			r := bk.FindParallel(q, 3, opts)
+			if len(r) != len(want) {
+				t.Fatalf("FindParallel(%s, %+v) found %d words, want %d", q, opts, len(r), len(want))
+			}
+			for i := range r {
+				if !bytes.Equal(r[i], want[i]) {
+					t.Fatalf("FindParallel(%s, %+v) found %q at %d, want %q", q, opts, r[i], i, want[i])
+				}
+			}
+		}
+	}
+
+	if r := New(levenshteinFromBytes).FindParallel([]byte("a"), 1, ParallelOptions{}); len(r) != 0 {
+		t.Fatalf("empty tree found %q", r)
+	}
+}
+
+func BenchmarkFindParallelLg(b *testing.B) {
+	bk := New(levenshteinFromBytes)
+	bk.AddAll(wordsOf(dictLg))
+
+	s := []string{}
+	for i := 0; i < b.N; i++ {
+		s = append(s, pick(dictLg))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bk.FindParallel([]byte(s[i]), 4, ParallelOptions{})
+	}
+}
@@ -0,0 +1,835 @@
+package bktree
+
+import (
+	"bytes"
+	"strings"
+)
+
+// The PhoneticEncoder type is a function returning the phonetic codes of a name, most likely first.
+type PhoneticEncoder func(name []byte) [][]byte
+
+// Codes adapts a single-code encoder such as Soundex or Metaphone to a PhoneticEncoder.
+func Codes(encode func(name []byte) []byte) PhoneticEncoder {
+	return func(name []byte) [][]byte {
+		return [][]byte{encode(name)}
+	}
+}
+
+// DoubleMetaphoneCodes is a PhoneticEncoder returning the primary and, when it
+// differs, the alternate Double Metaphone code of a name.
+func DoubleMetaphoneCodes(name []byte) [][]byte {
+	p, a := DoubleMetaphone(name)
+	if bytes.Equal(p, a) {
+		return [][]byte{p}
+	}
+	return [][]byte{p, a}
+}
+
+// editDistance is the Levenshtein distance between phonetic codes.
+var editDistance = WeightedEdit(EditCosts{})
+
+// Phonetic returns a Metric measuring the edit distance between the phonetic
+// codes of two names. Names with the same code are at distance 0.
+func Phonetic(encode func(name []byte) []byte) Metric {
+	return func(a, b []byte) int {
+		return editDistance(encode(a), encode(b))
+	}
+}
+
+// PhoneticIndex is a BK-tree over the phonetic codes of names. Names are
+// returned as they were added, while both names and queries are placed by
+// their codes, so that Find answers "names that sound like this one".
+//
+// Nodes hold a code in Node.Data and the name in Node.Value, rather than the
+// name in Node.Data under a metric over names, so that a name with several
+// codes can be reached by each of them. Such a name is stored once per code:
+// Len counts it once, but the tree returned by Tree, its Len, Stats and saved
+// files count every code.
+type PhoneticIndex struct {
+	tree   *BKTree
+	encode PhoneticEncoder
+}
+
+// NewPhoneticIndex returns an empty index keyed by the codes of encode.
+// Names with several codes are indexed under each of them.
+func NewPhoneticIndex(encode PhoneticEncoder) *PhoneticIndex {
+	return &PhoneticIndex{
+		tree:   New(editDistance),
+		encode: encode,
+	}
+}
+
+// Add inserts a name into the index.
+func (p *PhoneticIndex) Add(name []byte) {
+	for _, code := range p.encode(name) {
+		p.tree.add(code, name)
+	}
+}
+
+// Len returns the number of names in the index, a name added several times
+// being counted as many times.
+func (p *PhoneticIndex) Len() int {
+	n := 0
+	if root := p.tree.load().root; root != nil {
+		root.walk(func(e *node, _ int) bool {
+			if codes := p.encode(e.value()); len(codes) > 0 && bytes.Equal(e.Data, codes[0]) {
+				n++
+			}
+			return true
+		}, 0)
+	}
+	return n
+}
+
+// Find returns the names having a code within edit distance n of a code of
+// name. A name matched by several of its codes is returned as many times as
+// it was added.
+func (p *PhoneticIndex) Find(name []byte, n int64) [][]byte {
+	seen := map[*node]bool{}
+	first := map[string]string{} // Code under which each name was first matched
+	r := [][]byte{}
+	for _, code := range p.encode(name) {
+		p.search(code, n, func(e *node) {
+			if seen[e] {
+				return
+			}
+			seen[e] = true
+			w := e.value()
+			c, ok := first[string(w)]
+			if !ok {
+				c = string(e.Data)
+				first[string(w)] = c
+			}
+			if c == string(e.Data) {
+				r = append(r, w)
+			}
+		})
+	}
+	return r
+}
+
+// search calls fn for every node whose code is within edit distance n of code.
+func (p *PhoneticIndex) search(code []byte, n int64, fn func(e *node)) {
+	var visit func(e *node) bool
+	visit = func(e *node) bool {
+		d := int64(editDistance(e.Data, code))
+		if d <= n {
+			fn(e)
+		}
+		return e.near(d, d-n, d+n, visit)
+	}
+	if root := p.tree.load().root; root != nil {
+		visit(root)
+	}
+}
+
+// Tree returns the BK-tree backing the index, e.g. for saving it to a file.
+func (p *PhoneticIndex) Tree() *BKTree {
+	return p.tree
+}
+
+var soundexCodes = map[rune]byte{
+	'B': '1', 'F': '1', 'P': '1', 'V': '1',
+	'C': '2', 'G': '2', 'J': '2', 'K': '2', 'Q': '2', 'S': '2', 'X': '2', 'Z': '2',
+	'D': '3', 'T': '3',
+	'L': '4',
+	'M': '5', 'N': '5',
+	'R': '6',
+}
+
+// Soundex returns the American Soundex code of a name: its first letter
+// followed by three digits, e.g. "R163" for both "Robert" and "Rupert".
+// Characters other than ASCII letters are ignored.
+func Soundex(name []byte) []byte {
+	r := make([]byte, 0, 4)
+	var last byte
+	for _, c := range strings.ToUpper(string(name)) {
+		if c < 'A' || c > 'Z' {
+			continue
+		}
+		code := soundexCodes[c]
+		if len(r) == 0 {
+			r = append(r, byte(c))
+			last = code
+			continue
+		}
+		switch {
+		case c == 'H' || c == 'W':
+			// Does not separate letters with the same code
+		case code == 0:
+			last = 0 // Vowels separate letters with the same code
+		case code != last:
+			r = append(r, code)
+			last = code
+		}
+		if len(r) == 4 {
+			break
+		}
+	}
+	if len(r) == 0 {
+		return r
+	}
+	for len(r) < 4 {
+		r = append(r, '0')
+	}
+	return r
+}
+
+// Metaphone returns the original Metaphone code of a name, in which '0'
+// stands for "th". Characters other than ASCII letters are ignored.
+func Metaphone(name []byte) []byte {
+	w := []byte{}
+	for _, c := range strings.ToUpper(string(name)) {
+		if c >= 'A' && c <= 'Z' {
+			w = append(w, byte(c))
+		}
+	}
+	if len(w) == 0 {
+		return w
+	}
+
+	at := func(i int) byte {
+		if i < 0 || i >= len(w) {
+			return 0
+		}
+		return w[i]
+	}
+	vowel := func(c byte) bool {
+		return c != 0 && strings.IndexByte("AEIOU", c) >= 0
+	}
+	follows := func(i int, s string) bool {
+		return i >= 0 && i+len(s) <= len(w) && string(w[i:i+len(s)]) == s
+	}
+
+	r := []byte{}
+	i := 0
+	switch {
+	case follows(0, "AE"), follows(0, "GN"), follows(0, "KN"), follows(0, "PN"), follows(0, "WR"):
+		i = 1 // First letter is silent
+	case w[0] == 'X':
+		r = append(r, 'S')
+		i = 1
+	case follows(0, "WH"):
+		r = append(r, 'W')
+		i = 2
+	}
+
+	for ; i < len(w); i++ {
+		c := w[i]
+		if c == at(i-1) && c != 'C' {
+			continue
+		}
+		switch c {
+		case 'A', 'E', 'I', 'O', 'U':
+			if i == 0 {
+				r = append(r, c)
+			}
+		case 'B':
+			if !(at(i-1) == 'M' && i == len(w)-1) {
+				r = append(r, 'B')
+			}
+		case 'C':
+			switch {
+			case follows(i+1, "IA"):
+				r = append(r, 'X')
+			case at(i+1) == 'H':
+				if at(i-1) == 'S' {
+					r = append(r, 'K')
+				} else {
+					r = append(r, 'X')
+				}
+				i++
+			case at(i+1) == 'I' || at(i+1) == 'E' || at(i+1) == 'Y':
+				if at(i-1) != 'S' {
+					r = append(r, 'S')
+				}
+			default:
+				r = append(r, 'K')
+			}
+		case 'D':
+			if at(i+1) == 'G' && strings.IndexByte("EIY", at(i+2)) >= 0 {
+				r = append(r, 'J')
+				i += 2
+			} else {
+				r = append(r, 'T')
+			}
+		case 'G':
+			switch {
+			case at(i+1) == 'H' && !vowel(at(i+2)):
+				// Silent, as in "night" and "high"
+			case at(i+1) == 'N' && (i+2 == len(w) || follows(i+1, "NED") && i+4 == len(w)):
+				// Silent, as in "sign" and "signed"
+			case strings.IndexByte("EIY", at(i+1)) >= 0:
+				r = append(r, 'J')
+			default:
+				r = append(r, 'K')
+			}
+		case 'H':
+			if strings.IndexByte("CGPST", at(i-1)) >= 0 {
+				break // Part of a digraph handled by the previous letter
+			}
+			if vowel(at(i-1)) && !vowel(at(i+1)) {
+				break
+			}
+			r = append(r, 'H')
+		case 'K':
+			if at(i-1) != 'C' {
+				r = append(r, 'K')
+			}
+		case 'P':
+			if at(i+1) == 'H' {
+				r = append(r, 'F')
+			} else {
+				r = append(r, 'P')
+			}
+		case 'Q':
+			r = append(r, 'K')
+		case 'S':
+			switch {
+			case at(i+1) == 'H':
+				r = append(r, 'X')
+				i++
+			case follows(i+1, "IO"), follows(i+1, "IA"):
+				r = append(r, 'X')
+			default:
+				r = append(r, 'S')
+			}
+		case 'T':
+			switch {
+			case follows(i+1, "IA"), follows(i+1, "IO"):
+				r = append(r, 'X')
+			case at(i+1) == 'H':
+				r = append(r, '0')
+				i++
+			case follows(i+1, "CH"):
+				// Silent, as in "watch"
+			default:
+				r = append(r, 'T')
+			}
+		case 'V':
+			r = append(r, 'F')
+		case 'W', 'Y':
+			if vowel(at(i + 1)) {
+				r = append(r, c)
+			}
+		case 'X':
+			r = append(r, 'K', 'S')
+		case 'Z':
+			r = append(r, 'S')
+		default: // F, J, L, M, N, R
+			r = append(r, c)
+		}
+	}
+	return r
+}
+
+// doubleMetaphoneLength is the length of the codes returned by DoubleMetaphone.
+const doubleMetaphoneLength = 4
+
+// DoubleMetaphone returns the primary and alternate Double Metaphone codes of
+// a name, in which '0' stands for "th". The alternate code accounts for
+// spellings borrowed from other languages and equals the primary code
+// when there is no such ambiguity.
+func DoubleMetaphone(name []byte) (primary, alternate []byte) {
+	m := &doubleMetaphone{w: []rune(strings.ToUpper(strings.TrimSpace(string(name))))}
+	if len(m.w) == 0 {
+		return []byte{}, []byte{}
+	}
+	w := string(m.w)
+	m.slavoGermanic = strings.ContainsAny(w, "WK") || strings.Contains(w, "CZ")
+	m.encode()
+
+	primary = []byte(m.primary)
+	alternate = []byte(m.alternate)
+	if len(primary) > doubleMetaphoneLength {
+		primary = primary[:doubleMetaphoneLength]
+	}
+	if len(alternate) > doubleMetaphoneLength {
+		alternate = alternate[:doubleMetaphoneLength]
+	}
+	return primary, alternate
+}
+
+// doubleMetaphone holds the state of a Double Metaphone encoding.
+type doubleMetaphone struct {
+	w                  []rune
+	primary, alternate string
+	slavoGermanic      bool
+}
+
+// at returns the rune at i, or 0 when i is out of range.
+func (m *doubleMetaphone) at(i int) rune {
+	if i < 0 || i >= len(m.w) {
+		return 0
+	}
+	return m.w[i]
+}
+
+// has tells whether the n runes starting at i equal one of the given strings.
+func (m *doubleMetaphone) has(i, n int, ss ...string) bool {
+	if i < 0 || i+n > len(m.w) {
+		return false
+	}
+	sub := string(m.w[i : i+n])
+	for _, s := range ss {
+		if sub == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *doubleMetaphone) vowel(i int) bool {
+	return strings.ContainsRune("AEIOUY", m.at(i))
+}
+
+func (m *doubleMetaphone) add(p, a string) {
+	m.primary += p
+	m.alternate += a
+}
+
+func (m *doubleMetaphone) both(s string) {
+	m.add(s, s)
+}
+
+func (m *doubleMetaphone) done() bool {
+	return len(m.primary) >= doubleMetaphoneLength && len(m.alternate) >= doubleMetaphoneLength
+}
+
+// step returns 2 if the rune after i is c, and 1 otherwise.
+func (m *doubleMetaphone) step(i int, c rune) int {
+	if m.at(i+1) == c {
+		return 2
+	}
+	return 1
+}
+
+func (m *doubleMetaphone) encode() {
+	i := 0
+	if m.has(0, 2, "GN", "KN", "PN", "WR", "PS") {
+		i = 1
+	}
+	if m.at(0) == 'X' {
+		m.both("S")
+		i = 1
+	}
+
+	for !m.done() && i < len(m.w) {
+		switch m.at(i) {
+		case 'A', 'E', 'I', 'O', 'U', 'Y':
+			if i == 0 {
+				m.both("A")
+			}
+			i++
+		case 'B':
+			m.both("P")
+			i += m.step(i, 'B')
+		case 'Ç':
+			m.both("S")
+			i++
+		case 'C':
+			i = m.c(i)
+		case 'D':
+			i = m.d(i)
+		case 'F':
+			m.both("F")
+			i += m.step(i, 'F')
+		case 'G':
+			i = m.g(i)
+		case 'H':
+			if (i == 0 || m.vowel(i-1)) && m.vowel(i+1) {
+				m.both("H")
+				i += 2
+			} else {
+				i++
+			}
+		case 'J':
+			i = m.j(i)
+		case 'K':
+			m.both("K")
+			i += m.step(i, 'K')
+		case 'L':
+			i = m.l(i)
+		case 'M':
+			m.both("M")
+			if m.at(i+1) == 'M' || m.has(i-1, 3, "UMB") && (i+1 == len(m.w)-1 || m.has(i+2, 2, "ER")) {
+				i += 2
+			} else {
+				i++
+			}
+		case 'N':
+			m.both("N")
+			i += m.step(i, 'N')
+		case 'Ñ':
+			m.both("N")
+			i++
+		case 'P':
+			if m.at(i+1) == 'H' {
+				m.both("F")
+				i += 2
+			} else {
+				m.both("P")
+				if m.has(i+1, 1, "P", "B") {
+					i += 2
+				} else {
+					i++
+				}
+			}
+		case 'Q':
+			m.both("K")
+			i += m.step(i, 'Q')
+		case 'R':
+			if i == len(m.w)-1 && !m.slavoGermanic && m.has(i-2, 2, "IE") && !m.has(i-4, 2, "ME", "MA") {
+				m.add("", "R")
+			} else {
+				m.both("R")
+			}
+			i += m.step(i, 'R')
+		case 'S':
+			i = m.s(i)
+		case 'T':
+			i = m.t(i)
+		case 'V':
+			m.both("F")
+			i += m.step(i, 'V')
+		case 'W':
+			i = m.wr(i)
+		case 'X':
+			if i == 0 {
+				m.both("S")
+				i++
+				break
+			}
+			if !(i == len(m.w)-1 && (m.has(i-3, 3, "IAU", "EAU") || m.has(i-2, 2, "AU", "OU"))) {
+				m.both("KS")
+			}
+			if m.has(i+1, 1, "C", "X") {
+				i += 2
+			} else {
+				i++
+			}
+		case 'Z':
+			i = m.z(i)
+		default:
+			i++
+		}
+	}
+}
+
+func (m *doubleMetaphone) c(i int) int {
+	switch {
+	case m.cAsK(i):
+		m.both("K")
+		return i + 2
+	case i == 0 && m.has(i, 6, "CAESAR"):
+		m.both("S")
+		return i + 2
+	case m.has(i, 2, "CH"):
+		return m.ch(i)
+	case m.has(i, 2, "CZ") && !m.has(i-2, 4, "WICZ"):
+		m.add("S", "X")
+		return i + 2
+	case m.has(i+1, 3, "CIA"):
+		m.both("X")
+		return i + 3
+	case m.has(i, 2, "CC") && !(i == 1 && m.at(0) == 'M'):
+		if m.has(i+2, 1, "I", "E", "H") && !m.has(i+2, 2, "HU") {
+			if i == 1 && m.at(i-1) == 'A' || m.has(i-1, 5, "UCCEE", "UCCES") {
+				m.both("KS")
+			} else {
+				m.both("X")
+			}
+			return i + 3
+		}
+		m.both("K")
+		return i + 2
+	case m.has(i, 2, "CK", "CG", "CQ"):
+		m.both("K")
+		return i + 2
+	case m.has(i, 2, "CI", "CE", "CY"):
+		if m.has(i, 3, "CIO", "CIE", "CIA") {
+			m.add("S", "X")
+		} else {
+			m.both("S")
+		}
+		return i + 2
+	}
+
+	m.both("K")
+	switch {
+	case m.has(i+1, 2, " C", " Q", " G"):
+		return i + 3
+	case m.has(i+1, 1, "C", "K", "Q") && !m.has(i+1, 2, "CE", "CI"):
+		return i + 2
+	}
+	return i + 1
+}
+
+// cAsK tells whether the C at i is hard in a Germanic "ACH", as in "Bacher".
+func (m *doubleMetaphone) cAsK(i int) bool {
+	switch {
+	case m.has(i, 4, "CHIA"):
+		return true
+	case i <= 1, m.vowel(i - 2), !m.has(i-1, 3, "ACH"):
+		return false
+	}
+	c := m.at(i + 2)
+	return c != 'I' && c != 'E' || m.has(i-2, 6, "BACHER", "MACHER")
+}
+
+func (m *doubleMetaphone) ch(i int) int {
+	switch {
+	case i > 0 && m.has(i, 4, "CHAE"):
+		m.add("K", "X")
+	case i == 0 && (m.has(i+1, 5, "HARAC", "HARIS") || m.has(i+1, 3, "HOR", "HYM", "HIA", "HEM")) && !m.has(0, 5, "CHORE"):
+		m.both("K") // Greek roots, as in "chemistry"
+	case m.has(0, 4, "VAN ", "VON ") || m.has(0, 3, "SCH") ||
+		m.has(i-2, 6, "ORCHES", "ARCHIT", "ORCHID") ||
+		m.has(i+2, 1, "T", "S") ||
+		(m.has(i-1, 1, "A", "O", "U", "E") || i == 0) &&
+			(m.has(i+2, 1, "L", "R", "N", "M", "B", "H", "F", "V", "W", " ") || i+1 == len(m.w)-1):
+		m.both("K") // Germanic, as in "Bach"
+	case i > 0:
+		if m.has(0, 2, "MC") {
+			m.both("K")
+		} else {
+			m.add("X", "K")
+		}
+	default:
+		m.both("X")
+	}
+	return i + 2
+}
+
+func (m *doubleMetaphone) d(i int) int {
+	switch {
+	case m.has(i, 2, "DG"):
+		if m.has(i+2, 1, "I", "E", "Y") {
+			m.both("J")
+			return i + 3
+		}
+		m.both("TK")
+		return i + 2
+	case m.has(i, 2, "DT", "DD"):
+		m.both("T")
+		return i + 2
+	}
+	m.both("T")
+	return i + 1
+}
+
+func (m *doubleMetaphone) g(i int) int {
+	switch {
+	case m.at(i+1) == 'H':
+		return m.gh(i)
+	case m.at(i+1) == 'N':
+		switch {
+		case i == 1 && m.vowel(0) && !m.slavoGermanic:
+			m.add("KN", "N")
+		case !m.has(i+2, 2, "EY") && m.at(i+1) != 'Y' && !m.slavoGermanic:
+			m.add("N", "KN")
+		default:
+			m.both("KN")
+		}
+		return i + 2
+	case m.has(i+1, 2, "LI") && !m.slavoGermanic:
+		m.add("KL", "L")
+		return i + 2
+	case i == 0 && (m.at(i+1) == 'Y' || m.has(i+1, 2, "ES", "EP", "EB", "EL", "EY", "IB", "IL", "IN", "IE", "EI", "ER")):
+		m.add("K", "J")
+		return i + 2
+	case (m.has(i+1, 2, "ER") || m.at(i+1) == 'Y') && !m.has(0, 6, "DANGER", "RANGER", "MANGER") &&
+		!m.has(i-1, 1, "E", "I") && !m.has(i-1, 3, "RGY", "OGY"):
+		m.add("K", "J")
+		return i + 2
+	case m.has(i+1, 1, "E", "I", "Y") || m.has(i-1, 4, "AGGI", "OGGI"):
+		switch {
+		case m.has(0, 4, "VAN ", "VON ") || m.has(0, 3, "SCH") || m.has(i+1, 2, "ET"):
+			m.both("K")
+		case m.has(i+1, 3, "IER"):
+			m.both("J")
+		default:
+			m.add("J", "K")
+		}
+		return i + 2
+	case m.at(i+1) == 'G':
+		m.both("K")
+		return i + 2
+	}
+	m.both("K")
+	return i + 1
+}
+
+func (m *doubleMetaphone) gh(i int) int {
+	switch {
+	case i > 0 && !m.vowel(i-1):
+		m.both("K")
+	case i == 0:
+		if m.at(i+2) == 'I' {
+			m.both("J")
+		} else {
+			m.both("K")
+		}
+	case i > 1 && m.has(i-2, 1, "B", "H", "D") ||
+		i > 2 && m.has(i-3, 1, "B", "H", "D") ||
+		i > 3 && m.has(i-4, 1, "B", "H"):
+		// Silent, as in "Hugh" and "bough"
+	case i > 2 && m.at(i-1) == 'U' && m.has(i-3, 1, "C", "G", "L", "R", "T"):
+		m.both("F") // As in "laugh"
+	case m.at(i-1) != 'I':
+		m.both("K")
+	}
+	return i + 2
+}
+
+func (m *doubleMetaphone) j(i int) int {
+	if m.has(i, 4, "JOSE") || m.has(0, 4, "SAN ") {
+		if i == 0 && m.at(i+4) == ' ' || len(m.w) == 4 || m.has(0, 4, "SAN ") {
+			m.both("H")
+		} else {
+			m.add("J", "H")
+		}
+		return i + 1
+	}
+
+	switch {
+	case i == 0:
+		m.add("J", "A")
+	case m.vowel(i-1) && !m.slavoGermanic && (m.at(i+1) == 'A' || m.at(i+1) == 'O'):
+		m.add("J", "H")
+	case i == len(m.w)-1:
+		m.add("J", "")
+	case !m.has(i+1, 1, "L", "T", "K", "S", "N", "M", "B", "Z") && !m.has(i-1, 1, "S", "K", "L"):
+		m.both("J")
+	}
+	return i + m.step(i, 'J')
+}
+
+func (m *doubleMetaphone) l(i int) int {
+	if m.at(i+1) != 'L' {
+		m.both("L")
+		return i + 1
+	}
+	n := len(m.w)
+	if i == n-3 && m.has(i-1, 4, "ILLO", "ILLA", "ALLE") ||
+		(m.has(n-2, 2, "AS", "OS") || m.has(n-1, 1, "A", "O")) && m.has(i-1, 4, "ALLE") {
+		m.add("L", "") // Spanish, as in "Cabrillo"
+	} else {
+		m.both("L")
+	}
+	return i + 2
+}
+
+func (m *doubleMetaphone) s(i int) int {
+	switch {
+	case m.has(i-1, 3, "ISL", "YSL"):
+		return i + 1 // Silent, as in "island"
+	case i == 0 && m.has(i, 5, "SUGAR"):
+		m.add("X", "S")
+		return i + 1
+	case m.has(i, 2, "SH"):
+		if m.has(i+1, 4, "HEIM", "HOEK", "HOLM", "HOLZ") {
+			m.both("S")
+		} else {
+			m.both("X")
+		}
+		return i + 2
+	case m.has(i, 3, "SIO", "SIA") || m.has(i, 4, "SIAN"):
+		if m.slavoGermanic {
+			m.both("S")
+		} else {
+			m.add("S", "X")
+		}
+		return i + 3
+	case i == 0 && m.has(i+1, 1, "M", "N", "L", "W") || m.has(i+1, 1, "Z"):
+		m.add("S", "X")
+		return i + m.step(i, 'Z')
+	case m.has(i, 2, "SC"):
+		return m.sc(i)
+	}
+
+	if i == len(m.w)-1 && m.has(i-2, 2, "AI", "OI") {
+		m.add("", "S") // French, as in "Dubois"
+	} else {
+		m.both("S")
+	}
+	if m.has(i+1, 1, "S", "Z") {
+		return i + 2
+	}
+	return i + 1
+}
+
+func (m *doubleMetaphone) sc(i int) int {
+	switch {
+	case m.at(i+2) == 'H':
+		switch {
+		case m.has(i+3, 2, "ER", "EN"):
+			m.add("X", "SK")
+		case m.has(i+3, 2, "OO", "UY", "ED", "EM"):
+			m.both("SK")
+		case i == 0 && !m.vowel(3) && m.at(3) != 'W':
+			m.add("X", "S")
+		default:
+			m.both("X")
+		}
+	case m.has(i+2, 1, "I", "E", "Y"):
+		m.both("S")
+	default:
+		m.both("SK")
+	}
+	return i + 3
+}
+
+func (m *doubleMetaphone) t(i int) int {
+	switch {
+	case m.has(i, 4, "TION"), m.has(i, 3, "TIA", "TCH"):
+		m.both("X")
+		return i + 3
+	case m.has(i, 2, "TH") || m.has(i, 3, "TTH"):
+		if m.has(i+2, 2, "OM", "AM") || m.has(0, 4, "VAN ", "VON ") || m.has(0, 3, "SCH") {
+			m.both("T")
+		} else {
+			m.add("0", "T")
+		}
+		return i + 2
+	}
+	m.both("T")
+	if m.has(i+1, 1, "T", "D") {
+		return i + 2
+	}
+	return i + 1
+}
+
+func (m *doubleMetaphone) wr(i int) int {
+	switch {
+	case m.has(i, 2, "WR"):
+		m.both("R")
+		return i + 2
+	case i == 0 && (m.vowel(i+1) || m.has(i, 2, "WH")):
+		if m.vowel(i + 1) {
+			m.add("A", "F")
+		} else {
+			m.both("A")
+		}
+	case i == len(m.w)-1 && m.vowel(i-1) ||
+		m.has(i-1, 5, "EWSKI", "EWSKY", "OWSKI", "OWSKY") || m.has(0, 3, "SCH"):
+		m.add("", "F") // Polish, as in "Filipowicz"
+	case m.has(i, 4, "WICZ", "WITZ"):
+		m.add("TS", "FX")
+		return i + 4
+	}
+	return i + 1
+}
+
+func (m *doubleMetaphone) z(i int) int {
+	if m.at(i+1) == 'H' {
+		m.both("J") // Chinese, as in "Zhao"
+		return i + 2
+	}
+	if m.has(i+1, 2, "ZO", "ZI", "ZA") || m.slavoGermanic && i > 0 && m.at(i-1) != 'T' {
+		m.add("S", "TS")
+	} else {
+		m.both("S")
+	}
+	return i + m.step(i, 'Z')
+}
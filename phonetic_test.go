@@ -0,0 +1,122 @@
+package bktree
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestSoundex(t *testing.T) {
+	cases := map[string]string{
+		"Robert":   "R163",
+		"Rupert":   "R163",
+		"Tymczak":  "T522",
+		"Pfister":  "P236",
+		"Ashcraft": "A261",
+		"Lee":      "L000",
+		"":         "",
+	}
+	for name, want := range cases {
+		if got := string(Soundex([]byte(name))); got != want {
+			t.Errorf("Soundex(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestMetaphone(t *testing.T) {
+	cases := map[string]string{
+		"Stephen": "STFN",
+		"Steven":  "STFN",
+		"Knight":  "NT",
+		"Philip":  "FLP",
+		"Smith":   "SM0",
+		"Xavier":  "SFR",
+		"Dumb":    "TM",
+	}
+	for name, want := range cases {
+		if got := string(Metaphone([]byte(name))); got != want {
+			t.Errorf("Metaphone(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestDoubleMetaphone(t *testing.T) {
+	cases := map[string][2]string{
+		"Smith":     {"SM0", "XMT"},
+		"Schmidt":   {"XMT", "SMT"},
+		"Jose":      {"HS", "HS"},
+		"Xavier":    {"SF", "SFR"},
+		"Caesar":    {"SSR", "SSR"},
+		"Catherine": {"K0RN", "KTRN"},
+		"Gallegos":  {"KLKS", "KKS"},
+		"Zhao":      {"J", "J"},
+		"Arnow":     {"ARN", "ARNF"},
+	}
+	for name, want := range cases {
+		p, a := DoubleMetaphone([]byte(name))
+		if string(p) != want[0] || string(a) != want[1] {
+			t.Errorf("DoubleMetaphone(%q) = %q, %q, want %q, %q", name, p, a, want[0], want[1])
+		}
+	}
+}
+
+func TestPhoneticIndex(t *testing.T) {
+	names := []string{"Stephen", "Steven", "Stefan", "Smith", "Schmidt", "Catherine", "Kathryn", "Robert"}
+
+	find := func(p *PhoneticIndex, name string, n int64) []string {
+		r := []string{}
+		for _, w := range p.Find([]byte(name), n) {
+			r = append(r, string(w))
+		}
+		sort.Strings(r)
+		return r
+	}
+	equal := func(a, b []string) bool {
+		if len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	p := NewPhoneticIndex(Codes(Metaphone))
+	for _, n := range names {
+		p.Add([]byte(n))
+	}
+	if r, want := find(p, "Stephen", 0), []string{"Stefan", "Stephen", "Steven"}; !equal(r, want) {
+		t.Errorf("Metaphone index found %q, want %q", r, want)
+	}
+
+	p = NewPhoneticIndex(DoubleMetaphoneCodes)
+	for _, n := range names {
+		p.Add([]byte(n))
+	}
+	if r, want := find(p, "Schmidt", 0), []string{"Schmidt", "Smith"}; !equal(r, want) {
+		t.Errorf("Double Metaphone index found %q, want %q", r, want)
+	}
+	if r, want := find(p, "Katrine", 1), []string{"Catherine", "Kathryn"}; !equal(r, want) {
+		t.Errorf("Double Metaphone index found %q, want %q", r, want)
+	}
+
+	// Names with two codes are counted and found once per Add.
+	if p.Len() != len(names) || p.Tree().Len() <= len(names) {
+		t.Errorf("index of %d names has Len %d over a tree of %d codes", len(names), p.Len(), p.Tree().Len())
+	}
+	p.Add([]byte("Schmidt"))
+	if r, want := find(p, "Smith", 0), []string{"Schmidt", "Schmidt", "Smith"}; !equal(r, want) || p.Len() != len(names)+1 {
+		t.Errorf("Double Metaphone index found %q, want %q", r, want)
+	}
+}
+
+func TestPhonetic(t *testing.T) {
+	m := Phonetic(Soundex)
+	if d := m([]byte("Robert"), []byte("Rupert")); d != 0 {
+		t.Errorf("distance between Robert and Rupert is %d, want 0", d)
+	}
+	if d := m([]byte("Robert"), []byte("Rubin")); d != 2 {
+		t.Errorf("distance between Robert and Rubin is %d, want 2", d)
+	}
+}
@@ -0,0 +1,192 @@
+package bktree
+
+import (
+	"bytes"
+	"maps"
+	"math"
+	"math/rand/v2"
+	"slices"
+)
+
+// RebuildOptions controls how Rebuild re-creates a tree.
+type RebuildOptions struct {
+	Candidates int // Pivots compared for each subtree, 8 if zero
+
+	// Online builds the new tree from copies of the nodes, so that the tree
+	// can be searched, or added to, while Rebuild runs; it then replaces the
+	// old tree at once. Otherwise the nodes are relinked in place, which needs
+	// no extra memory but leaves the tree unusable until Rebuild returns.
+	Online bool
+
+	Sample [][]byte // Queries measuring the search cost, up to 100 stored words if nil
+	Radius int64    // Radius of the sample queries
+}
+
+// RebuildReport compares the cost of searching a tree before and after Rebuild.
+type RebuildReport struct {
+	Words      int
+	CostBefore float64 // Average metric calls per sample query on the old tree
+	CostAfter  float64 // Average metric calls per sample query on the new tree
+
+	// Discarded is set when the tree was cleared or replaced during an online
+	// rebuild, in which case the rebuilt tree is dropped.
+	Discarded bool
+}
+
+// Rebuild re-creates the tree from its current words. Trees grown by Add keep
+// their earliest words at the top, however far they lie from the others;
+// Rebuild instead picks as the root of each subtree the candidate closest on
+// average to the other words, which makes searches visit fewer nodes.
+//
+// Words added during an online rebuild are inserted into the new tree before
+// it replaces the old one.
+func (t *BKTree) Rebuild(opts RebuildOptions) RebuildReport {
+	t.mu.Lock()
+	old := t.words.Load()
+	if old == nil || old.root == nil {
+		t.mu.Unlock()
+		return RebuildReport{}
+	}
+	nodes := []*node{}
+	old.root.walk(func(e *node, _ int) bool {
+		nodes = append(nodes, e)
+		return true
+	}, 0)
+	sample := opts.Sample
+	if sample == nil {
+		for i := 0; i < len(nodes); i += max(len(nodes)/100, 1) {
+			sample = append(sample, nodes[i].value())
+		}
+	}
+	r := RebuildReport{Words: len(nodes), CostBefore: t.cost(old.root, sample, opts.Radius)}
+
+	b := &builder{metric: t.Metric, candidates: opts.Candidates, rand: rand.New(rand.NewPCG(1, 2))}
+	if b.candidates <= 0 {
+		b.candidates = 8
+	}
+
+	if !opts.Online {
+		defer t.mu.Unlock()
+		for _, e := range nodes {
+			e.Children = e.Children[:0]
+		}
+		root := b.build(nodes, true)
+		t.words.Store(&contents{root: root, size: old.size, index: old.index})
+		t.dirty = true
+		r.CostAfter = t.cost(root, sample, opts.Radius)
+		return r
+	}
+
+	for i, e := range nodes {
+		nodes[i] = &node{Data: e.Data, Value: e.Value}
+	}
+	t.rebuilding = true
+	t.mu.Unlock()
+
+	root := b.build(nodes, true)
+	r.CostAfter = t.cost(root, sample, opts.Radius)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	pending := t.pending
+	t.rebuilding = false
+	t.pending = nil
+	if t.words.Load() != old {
+		r.Discarded = true
+		return r
+	}
+	// The old contents already index the pending words.
+	for _, p := range pending {
+		root.insert(newNode(p[0], p[1]), t.Metric)
+	}
+	t.words.Store(&contents{root: root, size: old.size, index: old.index})
+	t.dirty = true
+	return r
+}
+
+// cost returns the average number of metric calls made searching the tree
+// rooted at root for each word of sample.
+func (t *BKTree) cost(root *node, sample [][]byte, radius int64) float64 {
+	if len(sample) == 0 {
+		return 0
+	}
+	calls := 0
+	for _, w := range sample {
+		q := t.query(w, 0, radius, func(Match) bool { return true })
+		if q.float != nil {
+			root.searchFloat(q)
+		} else {
+			root.search(q)
+		}
+		calls += q.calls
+	}
+	return float64(calls) / float64(len(sample))
+}
+
+// minPivotChoice is the smallest subtree for which Rebuild compares pivots;
+// below it the choice barely affects the cost of searches.
+const minPivotChoice = 16
+
+// pivotSample is the number of words against which pivots are compared.
+const pivotSample = 16
+
+type builder struct {
+	metric     Metric
+	candidates int
+	rand       *rand.Rand
+}
+
+// build links nodes into a tree and returns its root. Pivots are compared
+// when choose is set; it is not for copies of a single key, which are all
+// alike. Groups at distance 0 hold only copies under metrics with
+// d(a, b) == 0 only for a == b, but distinct keys under quantized ones.
+func (b *builder) build(nodes []*node, choose bool) *node {
+	if choose && len(nodes) >= minPivotChoice {
+		p := b.pivot(nodes)
+		nodes[0], nodes[p] = nodes[p], nodes[0]
+	}
+	root := nodes[0]
+
+	groups := map[int64][]*node{}
+	for _, n := range nodes[1:] {
+		d := int64(b.metric(root.Data, n.Data))
+		groups[d] = append(groups[d], n)
+	}
+	for _, d := range slices.Sorted(maps.Keys(groups)) {
+		g := groups[d]
+		root.Children = append(root.Children, edge{d, b.build(g, d != 0 || !copies(g))})
+	}
+	return root
+}
+
+// copies reports whether nodes all hold the same key.
+func copies(nodes []*node) bool {
+	for _, n := range nodes[1:] {
+		if !bytes.Equal(n.Data, nodes[0].Data) {
+			return false
+		}
+	}
+	return true
+}
+
+// pivot returns the index of the candidate among nodes closest on average to
+// a sample of the nodes, an approximation of their medoid.
+func (b *builder) pivot(nodes []*node) int {
+	sample := make([]*node, pivotSample)
+	for i := range sample {
+		sample[i] = nodes[b.rand.IntN(len(nodes))]
+	}
+
+	best, bestSum := 0, math.MaxInt
+	for range b.candidates {
+		c := b.rand.IntN(len(nodes))
+		sum := 0
+		for _, s := range sample {
+			sum += b.metric(nodes[c].Data, s.Data)
+		}
+		if sum < bestSum {
+			best, bestSum = c, sum
+		}
+	}
+	return best
+}
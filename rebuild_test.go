@@ -0,0 +1,121 @@
+package bktree
+
+import (
+	"bytes"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestRebuild(t *testing.T) {
+	for _, online := range []bool{false, true} {
+		bk := New(levenshteinFromBytes)
+		if r := bk.Rebuild(RebuildOptions{Online: online}); r.Words != 0 {
+			t.Fatalf("rebuilding an empty tree returned %+v", r)
+		}
+
+		// Adding the longest words first puts words far from all others at the top.
+		words := wordsOf(dictLg)
+		slices.SortStableFunc(words, func(a, b []byte) int { return len(b) - len(a) })
+		bk.AddAll(words)
+		bk.Add(words[0])
+
+		queries := [][]byte{}
+		for _, w := range dictSm[:20] {
+			queries = append(queries, []byte(mess(w, 2)))
+		}
+		want := [][][]byte{}
+		for _, q := range queries {
+			want = append(want, bk.Find(q, 2))
+		}
+
+		r := bk.Rebuild(RebuildOptions{Online: online, Sample: queries, Radius: 2})
+		if r.Words != len(words)+1 || r.Discarded {
+			t.Fatalf("Rebuild returned %+v", r)
+		}
+		if r.CostAfter >= r.CostBefore {
+			t.Errorf("rebuilt tree costs %.1f metric calls per query, %.1f before", r.CostAfter, r.CostBefore)
+		}
+		if err := bk.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		if bk.Len() != len(words)+1 || bk.Stats().Nodes != bk.Len() {
+			t.Fatalf("rebuilt tree holds %d words, Len %d", bk.Stats().Nodes, bk.Len())
+		}
+		for i, q := range queries {
+			r := bk.Find(q, 2)
+			slices.SortFunc(r, bytes.Compare)
+			slices.SortFunc(want[i], bytes.Compare)
+			if !slices.EqualFunc(r, want[i], bytes.Equal) {
+				t.Fatalf("online: %v, Find(%s) found %q after rebuild, %q before", online, q, r, want[i])
+			}
+		}
+	}
+}
+
+func TestRebuildFloat(t *testing.T) {
+	// Bucket 0 of the root holds the distinct words 40 to 59 or so.
+	bk := NewFloat(absDiff, 10)
+	for i := 0; i < 100; i++ {
+		bk.Add([]byte(strconv.Itoa(i)))
+	}
+	bk.Rebuild(RebuildOptions{})
+	root := bk.load().root
+	c := root.child(0)
+	if c == nil {
+		t.Fatalf("root %s has no child in bucket 0", root.Data)
+	}
+	if d := absDiff(c.Data, root.Data); d > 5 {
+		t.Fatalf("bucket 0 of root %s is rooted at %s, not near its center", root.Data, c.Data)
+	}
+}
+
+func TestRebuildOnline(t *testing.T) {
+	bk := New(levenshteinFromBytes)
+	bk.AddAll(wordsOf(dictSm))
+
+	// Searches run during an online rebuild.
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		bk.Rebuild(RebuildOptions{Online: true})
+	}()
+	for _, w := range dictSm[:50] {
+		if r := bk.Find([]byte(w), 0); len(r) == 0 {
+			t.Fatalf("%s not found during the rebuild", w)
+		}
+	}
+	wg.Wait()
+
+	// Words may also be added during an online rebuild, and end up in the new tree.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		bk.Rebuild(RebuildOptions{Online: true})
+	}()
+	extra := []string{"zebra", "zebras", "quixotic"}
+	for _, w := range extra {
+		bk.Add([]byte(w))
+	}
+	wg.Wait()
+
+	if bk.Len() != len(dictSm)+len(extra) || bk.Stats().Nodes != bk.Len() {
+		t.Fatalf("tree holds %d words, Len %d", bk.Stats().Nodes, bk.Len())
+	}
+	for _, w := range extra {
+		if r := bk.Find([]byte(w), 0); len(r) != 1 {
+			t.Fatalf("word %s added during the rebuild found %d times", w, len(r))
+		}
+	}
+	if err := bk.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	bk.Clear()
+	bk.Add([]byte("alone"))
+	if r := bk.Rebuild(RebuildOptions{Online: true}); r.Words != 1 || bk.Len() != 1 {
+		t.Fatalf("Rebuild returned %+v for a tree of %d words", r, bk.Len())
+	}
+}
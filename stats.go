@@ -0,0 +1,91 @@
+package bktree
+
+import (
+	"time"
+	"unsafe"
+)
+
+// TreeStats describes the shape of a BK-tree. A well balanced tree is shallow
+// with a wide fan-out near the root; long duplicate chains or a depth close to
+// the number of nodes hint at a bad insertion order or a weak metric.
+type TreeStats struct {
+	Nodes    int
+	MaxDepth int     // Depth of the deepest node, the root being at depth 0
+	AvgDepth float64 // Average depth of the nodes
+
+	FanOut    map[int]int   // Number of nodes by number of children
+	Distances map[int64]int // Number of edges by distance between parent and child
+
+	Duplicates            int // Nodes at distance 0 from their parent
+	DuplicateChains       int // Maximal runs of edges at distance 0
+	LongestDuplicateChain int // Number of edges in the longest such run
+
+	MemoryBytes int // Rough estimate of the memory held by the tree and its index, if any
+}
+
+// Approximate sizes of runtime structures, for TreeStats.MemoryBytes.
+const (
+	mapHeaderBytes = 48
+	mapEntryBytes  = 24 // Key, value and control overhead
+	sliceBytes     = int(unsafe.Sizeof([]byte(nil)))
+	edgeBytes      = int(unsafe.Sizeof(edge{}))
+	stringBytes    = int(unsafe.Sizeof(""))
+)
+
+// Stats walks the BK-tree and returns statistics about its shape.
+func (t *BKTree) Stats() TreeStats {
+	s := TreeStats{
+		FanOut:    map[int]int{},
+		Distances: map[int64]int{},
+	}
+	c := t.load()
+	if c.root == nil {
+		return s
+	}
+
+	depths := 0
+	var visit func(e *node, depth, chain int)
+	visit = func(e *node, depth, chain int) {
+		s.Nodes++
+		depths += depth
+		s.MaxDepth = max(s.MaxDepth, depth)
+		s.FanOut[len(e.Children)]++
+		s.MemoryBytes += int(unsafe.Sizeof(*e)) + cap(e.Data) + cap(e.Value) + cap(e.Children)*edgeBytes
+
+		if e.child(0) != nil && chain == 0 {
+			s.DuplicateChains++
+		}
+		for _, c := range e.Children {
+			if c.Node == nil {
+				continue
+			}
+			s.Distances[c.Dist]++
+			if c.Dist == 0 {
+				s.Duplicates++
+				s.LongestDuplicateChain = max(s.LongestDuplicateChain, chain+1)
+				visit(c.Node, depth+1, chain+1)
+			} else {
+				visit(c.Node, depth+1, 0)
+			}
+		}
+	}
+	visit(c.root, 0, 0)
+	s.AvgDepth = float64(depths) / float64(s.Nodes)
+
+	if c.index != nil {
+		s.MemoryBytes += mapHeaderBytes
+	}
+	for k, v := range c.index {
+		s.MemoryBytes += mapEntryBytes + stringBytes + len(k) + sliceBytes + cap(v)*sliceBytes
+	}
+	return s
+}
+
+// QueryStats describes the work done by a search.
+type QueryStats struct {
+	Visited     int // Nodes visited
+	MetricCalls int // Metric evaluations
+	Pruned      int // Children of visited nodes skipped as unable to hold matches
+	Results     int // Words found
+	Elapsed     time.Duration
+}
@@ -0,0 +1,79 @@
+package bktree
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	bk := New(levenshteinFromBytes)
+	if s := bk.Stats(); s.Nodes != 0 || s.MemoryBytes != 0 {
+		t.Fatalf("empty tree has stats %+v", s)
+	}
+
+	for _, w := range []string{"book", "books", "cake", "boo", "cape", "cart", "book", "book", "cake"} {
+		bk.Add([]byte(w))
+	}
+	s := bk.Stats()
+	if s.Nodes != 9 || s.Nodes != bk.Len() {
+		t.Errorf("Nodes = %d, want 9", s.Nodes)
+	}
+	// book -0-> book -0-> book, book -1-> books -2-> boo; book -4-> cake -0-> cake, cake -1-> cape, cake -2-> cart
+	if s.MaxDepth != 2 {
+		t.Errorf("MaxDepth = %d, want 2", s.MaxDepth)
+	}
+	if want := 13.0 / 9; s.AvgDepth != want {
+		t.Errorf("AvgDepth = %f, want %f", s.AvgDepth, want)
+	}
+	if s.FanOut[0] != 5 || s.FanOut[1] != 2 || s.FanOut[3] != 2 {
+		t.Errorf("FanOut = %v", s.FanOut)
+	}
+	if s.Distances[0] != 3 || s.Distances[1] != 2 || s.Distances[2] != 2 || s.Distances[4] != 1 {
+		t.Errorf("Distances = %v", s.Distances)
+	}
+	if s.Duplicates != 3 || s.DuplicateChains != 2 || s.LongestDuplicateChain != 2 {
+		t.Errorf("Duplicates = %d, DuplicateChains = %d, LongestDuplicateChain = %d", s.Duplicates, s.DuplicateChains, s.LongestDuplicateChain)
+	}
+	if s.MemoryBytes <= 0 {
+		t.Errorf("MemoryBytes = %d", s.MemoryBytes)
+	}
+}
+
+func TestQueryStats(t *testing.T) {
+	var calls atomic.Int64
+	bk := New(func(a, b []byte) int {
+		calls.Add(1)
+		return levenshteinFromBytes(a, b)
+	})
+	bk.AddAll(wordsOf(dictLg))
+	var observed []QueryStats
+	bk.Observer = func(s QueryStats) { observed = append(observed, s) }
+
+	calls.Store(0)
+	var s QueryStats
+	r, _ := bk.FindWithOptions([]byte("mission"), 2, FindOptions{Stats: &s})
+	if n := int(calls.Load()); s.MetricCalls != n || s.Visited != n || s.Results != len(r) {
+		t.Errorf("stats %+v for a search making %d metric calls and finding %d words", s, n, len(r))
+	}
+	if s.Pruned == 0 || s.Visited+s.Pruned > bk.Len() {
+		t.Errorf("stats %+v for a tree of %d words", s, bk.Len())
+	}
+	if len(observed) != 1 || observed[0] != s {
+		t.Fatalf("Observer got %+v, want %+v", observed, s)
+	}
+
+	bk.Find([]byte("mission"), 2)
+	if len(observed) != 2 || observed[1].Results != s.Results || observed[1].Visited != s.Visited {
+		t.Fatalf("Observer got %+v after Find", observed)
+	}
+
+	p := bk.FindParallel([]byte("mission"), 2, ParallelOptions{Workers: 4, Depth: 2})
+	if len(observed) != 3 || observed[2].Results != len(p) || observed[2].Visited != s.Visited || observed[2].Pruned != s.Pruned {
+		t.Fatalf("Observer got %+v after FindParallel, want %+v", observed[len(observed)-1], s)
+	}
+
+	New(levenshteinFromBytes).FindWithOptions([]byte("mission"), 2, FindOptions{Stats: &s})
+	if s.Visited != 0 || s.Results != 0 {
+		t.Fatalf("empty tree has stats %+v", s)
+	}
+}
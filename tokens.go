@@ -0,0 +1,110 @@
+package bktree
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// The Tokenizer type is a function splitting a phrase into tokens.
+type Tokenizer func(data []byte) [][]byte
+
+// Words is a Tokenizer splitting a phrase around runs of white space.
+func Words(data []byte) [][]byte {
+	return bytes.Fields(data)
+}
+
+// TokenEdit returns a Metric counting the token insertions, deletions and
+// substitutions turning one phrase into the other, with phrases split by tokenize.
+func TokenEdit(tokenize Tokenizer) Metric {
+	return func(a, b []byte) int {
+		return TokenDistance(encodeTokens(tokenize(a)), encodeTokens(tokenize(b)))
+	}
+}
+
+// NewTokenTree returns an initialized BK-tree over phrases measured in token
+// edits. Keys are stored pre-tokenized, so searches compare token sequences
+// without splitting the stored phrases again, while results hold the phrases
+// as they were added. A Normalizer set on the tree is applied to phrases
+// before they are tokenized.
+func NewTokenTree(tokenize Tokenizer) *BKTree {
+	t := New(TokenDistance)
+	t.encode = Tokens(tokenize)
+	return t
+}
+
+// Tokens returns a Normalizer encoding a phrase as the sequence of its tokens,
+// to be measured by TokenDistance.
+func Tokens(tokenize Tokenizer) Normalizer {
+	return func(data []byte) []byte {
+		return encodeTokens(tokenize(data))
+	}
+}
+
+// stackTokens is the number of tokens up to which TokenDistance does not allocate.
+const stackTokens = 32
+
+// TokenDistance is a Metric counting token edits between phrases encoded by
+// Tokens. It reads the tokens in place, without decoding the phrases.
+func TokenDistance(a, b []byte) int {
+	nb := 0
+	for _, rest, ok := nextToken(b); ok; _, rest, ok = nextToken(rest) {
+		nb++
+	}
+
+	var buf [2 * (stackTokens + 1)]int
+	var prev, cur []int
+	if nb <= stackTokens {
+		prev, cur = buf[:nb+1], buf[stackTokens+1:][:nb+1]
+	} else {
+		prev, cur = make([]int, nb+1), make([]int, nb+1)
+	}
+	for j := range prev {
+		prev[j] = j
+	}
+
+	i := 0
+	for ta, ra, ok := nextToken(a); ok; ta, ra, ok = nextToken(ra) {
+		i++
+		cur[0] = i
+		j := 0
+		for tb, rb, ok := nextToken(b); ok; tb, rb, ok = nextToken(rb) {
+			j++
+			sub := prev[j-1]
+			if !bytes.Equal(ta, tb) {
+				sub++
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, sub)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[nb]
+}
+
+// encodeTokens prefixes every token with its length and concatenates them.
+func encodeTokens(toks [][]byte) []byte {
+	r := []byte{}
+	for _, tok := range toks {
+		r = binary.AppendUvarint(r, uint64(len(tok)))
+		r = append(r, tok...)
+	}
+	return r
+}
+
+// nextToken splits the first token encoded by Tokens off data, sharing memory
+// with it. It reports false at the end of data and on malformed keys.
+func nextToken(data []byte) (tok, rest []byte, ok bool) {
+	n, k := binary.Uvarint(data)
+	if k <= 0 || uint64(len(data)-k) < n {
+		return nil, nil, false
+	}
+	return data[k : k+int(n)], data[k+int(n):], true
+}
+
+// decodeTokens returns the tokens encoded by Tokens, sharing memory with data.
+func decodeTokens(data []byte) [][]byte {
+	r := [][]byte{}
+	for tok, rest, ok := nextToken(data); ok; tok, rest, ok = nextToken(rest) {
+		r = append(r, tok)
+	}
+	return r
+}
@@ -0,0 +1,86 @@
+package bktree
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+)
+
+func TestTokenEdit(t *testing.T) {
+	m := TokenEdit(Words)
+	cases := []struct {
+		a, b string
+		d    int
+	}{
+		{"red cotton shirt", "red cotton shirt", 0},
+		{"red cotton shirt", "red  cotton\tshirt ", 0},
+		{"red cotton shirt", "blue cotton shirt", 1},
+		{"red cotton shirt", "cotton shirt", 1},
+		{"red cotton shirt", "shirt cotton red", 2},
+		{"", "red shirt", 2},
+	}
+	for _, c := range cases {
+		if d := m([]byte(c.a), []byte(c.b)); d != c.d {
+			t.Errorf("TokenEdit(%q, %q) = %d, want %d", c.a, c.b, d, c.d)
+		}
+		enc := Tokens(Words)
+		if d := TokenDistance(enc([]byte(c.a)), enc([]byte(c.b))); d != c.d {
+			t.Errorf("TokenDistance(%q, %q) = %d, want %d", c.a, c.b, d, c.d)
+		}
+	}
+
+	a, b := Tokens(Words)([]byte("red cotton shirt")), Tokens(Words)([]byte("blue cotton shirt"))
+	if n := testing.AllocsPerRun(100, func() { TokenDistance(a, b) }); n != 0 {
+		t.Errorf("TokenDistance made %v allocations", n)
+	}
+
+	// Phrases with more tokens than fit on the stack.
+	long := bytes.Repeat([]byte("w "), 2*stackTokens)
+	if d := TokenDistance(Tokens(Words)(long), Tokens(Words)(long[4:])); d != 2 {
+		t.Errorf("TokenDistance between long phrases = %d, want 2", d)
+	}
+}
+
+func TestTokensBinary(t *testing.T) {
+	split := func(data []byte) [][]byte { return bytes.Split(data, []byte{','}) }
+	toks := decodeTokens(Tokens(split)([]byte("a b,,\x00c")))
+	if len(toks) != 3 || string(toks[0]) != "a b" || len(toks[1]) != 0 || string(toks[2]) != "\x00c" {
+		t.Fatalf("tokens do not round-trip: %q", toks)
+	}
+}
+
+func TestTokenTree(t *testing.T) {
+	bk := NewTokenTree(Words)
+	titles := []string{
+		"Acme red cotton shirt",
+		"Acme blue cotton shirt",
+		"Acme red linen shirt large",
+		"Globex stainless steel kettle",
+		"Globex steel kettle",
+	}
+	for _, w := range titles {
+		bk.Add([]byte(w))
+	}
+
+	r := []string{}
+	for _, w := range bk.Find([]byte("Acme  red cotton shirt"), 2) {
+		r = append(r, string(w))
+	}
+	sort.Strings(r)
+	want := []string{"Acme blue cotton shirt", "Acme red cotton shirt", "Acme red linen shirt large"}
+	if len(r) != len(want) {
+		t.Fatalf("found %q, want %q", r, want)
+	}
+	for i := range r {
+		if r[i] != want[i] {
+			t.Fatalf("found %q, want %q", r, want)
+		}
+	}
+
+	// A Normalizer is applied before tokenizing, without replacing the tokenizer.
+	bk.Normalizer = FoldCase
+	bk.Add([]byte("Initech Red Stapler"))
+	if r := bk.Find([]byte("initech  red stapler"), 0); len(r) != 1 || string(r[0]) != "Initech Red Stapler" {
+		t.Fatalf("Find with a Normalizer found %q", r)
+	}
+}
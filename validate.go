@@ -0,0 +1,103 @@
+package bktree
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// InvalidNode describes a node that breaks the structure of a tree.
+type InvalidNode struct {
+	Path   []int64 // Distances of the edges leading from the root to the node
+	Data   []byte  // Nil for nil children
+	Detail string
+}
+
+func (n InvalidNode) String() string {
+	return fmt.Sprintf("%s: %s", formatPath(n.Path), n.Detail)
+}
+
+// ValidationError lists the invalid nodes found by Validate.
+type ValidationError []InvalidNode
+
+// maxReported is the number of invalid nodes listed by ValidationError.Error.
+const maxReported = 10
+
+func (e ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "bktree: %d invalid nodes", len(e))
+	for i, n := range e {
+		if i == maxReported {
+			fmt.Fprintf(&b, "; and %d more", len(e)-i)
+			break
+		}
+		b.WriteString("; ")
+		b.WriteString(n.String())
+	}
+	return b.String()
+}
+
+// Validate checks that every child sits under the edge given by the metric,
+// i.e. that Metric(P.Data, C.Data) == d for every parent P and its child C
+// under key d, and that the tree holds no nil or misordered children and no
+// node reachable by more than one path, as cycles or shared subtrees. It returns a
+// ValidationError listing every offending node, or nil if the tree is sound.
+//
+// Validate is meant to be called after ReadFromFile, to catch files built with
+// a different metric; it evaluates the metric once per node.
+func (t *BKTree) Validate() error {
+	root := t.load().root
+	if root == nil {
+		return nil
+	}
+	v := &validation{metric: t.Metric, seen: map[*node][]int64{}, open: map[*node]bool{}}
+	v.visit(root, []int64{})
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+	return nil
+}
+
+type validation struct {
+	metric Metric
+	seen   map[*node][]int64 // Path at which each node was first reached
+	open   map[*node]bool    // Nodes on the path being visited
+	errs   ValidationError
+}
+
+func (v *validation) visit(e *node, path []int64) {
+	v.seen[e] = path
+	v.open[e] = true
+	defer delete(v.open, e)
+
+	for i, ch := range e.Children {
+		d, c := ch.Dist, ch.Node
+		p := append(path[:len(path):len(path)], d)
+		switch {
+		case i > 0 && d <= e.Children[i-1].Dist:
+			v.errs = append(v.errs, InvalidNode{Path: p, Detail: "children out of order"})
+		case c == nil:
+			v.errs = append(v.errs, InvalidNode{Path: p, Detail: "nil child"})
+		case v.open[c]:
+			v.errs = append(v.errs, InvalidNode{Path: p, Data: c.Data, Detail: "cycle back to " + formatPath(v.seen[c])})
+		case v.seen[c] != nil:
+			v.errs = append(v.errs, InvalidNode{Path: p, Data: c.Data, Detail: "node also reachable at " + formatPath(v.seen[c])})
+		default:
+			if l := int64(v.metric(e.Data, c.Data)); l != d {
+				v.errs = append(v.errs, InvalidNode{Path: p, Data: c.Data, Detail: fmt.Sprintf("stored at distance %d from %q, which the metric puts at %d", d, e.Data, l)})
+			}
+			v.visit(c, p)
+		}
+	}
+}
+
+// formatPath renders the edge distances leading from the root to a node.
+func formatPath(path []int64) string {
+	var b strings.Builder
+	b.WriteString("root")
+	for _, d := range path {
+		b.WriteByte('/')
+		b.WriteString(strconv.FormatInt(d, 10))
+	}
+	return b.String()
+}
@@ -0,0 +1,105 @@
+package bktree
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	if err := New(levenshteinFromBytes).Validate(); err != nil {
+		t.Fatalf("empty tree: %v", err)
+	}
+
+	filePath := filepath.Join(t.TempDir(), "tree.db")
+	bk := New(levenshteinFromBytes)
+	bk.AddAll(wordsOf(dictSm))
+	bk.Add([]byte(dictSm[0]))
+	if err := bk.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bk.SaveToFile(filePath); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := New(levenshteinFromBytes)
+	if err := loaded.ReadFromFile(filePath); err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.Validate(); err != nil {
+		t.Fatalf("tree read from file: %v", err)
+	}
+
+	// A file built with Levenshtein distances does not fit a length difference metric.
+	other := New(func(a, b []byte) int { return max(len(a)-len(b), len(b)-len(a)) })
+	if err := other.ReadFromFile(filePath); err != nil {
+		t.Fatal(err)
+	}
+	var verr ValidationError
+	if err := other.Validate(); !errors.As(err, &verr) || len(verr) == 0 {
+		t.Fatalf("tree read with the wrong metric: %v", err)
+	}
+	for _, n := range verr {
+		if !strings.Contains(n.Detail, "which the metric puts at") {
+			t.Fatalf("unexpected problem %s", n)
+		}
+	}
+}
+
+func TestValidateStructure(t *testing.T) {
+	bk := New(levenshteinFromBytes)
+	bk.AddAll(wordsOf([]string{"book", "books", "cake", "boo"}))
+	root := bk.load().root
+	books := root.child(1)
+	boo := books.child(2)
+
+	books.Children = append(books.Children, edge{7, nil})
+	boo.Children = append(boo.Children, edge{1, books})
+	root.Children = append(root.Children, edge{9, boo}, edge{5, boo})
+
+	var verr ValidationError
+	if err := bk.Validate(); !errors.As(err, &verr) {
+		t.Fatalf("Validate returned %v", err)
+	}
+	want := []string{
+		"root/1/2/1: cycle back to root/1",
+		"root/1/7: nil child",
+		"root/9: node also reachable at root/1/2",
+		"root/5: children out of order",
+	}
+	if len(verr) != len(want) {
+		t.Fatalf("Validate found %v, want %d problems", verr, len(want))
+	}
+	for i, n := range verr {
+		if n.String() != want[i] {
+			t.Errorf("problem %d is %q, want %q", i, n, want[i])
+		}
+	}
+}
+
+func TestValidateNilChild(t *testing.T) {
+	// Root "a" with an entry under key 3 holding no node.
+	filePath := filepath.Join(t.TempDir(), "tree.db")
+	if err := os.WriteFile(filePath, []byte{0x0a, 0x01, 'a', 0x12, 0x02, 0x08, 0x03}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	bk := New(levenshteinFromBytes)
+	if err := bk.ReadFromFile(filePath); err != nil {
+		t.Fatal(err)
+	}
+	if bk.Len() != 1 || len(bk.Find([]byte("abc"), 5)) != 1 {
+		t.Fatalf("tree with a nil child holds %d words", bk.Len())
+	}
+
+	var verr ValidationError
+	if err := bk.Validate(); !errors.As(err, &verr) || len(verr) != 1 || verr[0].String() != "root/3: nil child" {
+		t.Fatalf("Validate returned %v", err)
+	}
+
+	bk.Add([]byte("abcd"))
+	if err := bk.Validate(); err != nil || bk.Len() != 2 {
+		t.Fatalf("adding under the nil child left %d words, %v", bk.Len(), err)
+	}
+}